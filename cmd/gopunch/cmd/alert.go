@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/TheRemyyy/gopunch/internal/alerter"
+)
+
+var alertTestNotifier string
+
+var alertCmd = &cobra.Command{
+	Use:   "alert",
+	Short: "Manage and test alerting",
+}
+
+var alertTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a sample failure and recovery through the notifiers",
+	Long: `Send a sample failure followed by a recovery through every notifier
+configured in the alerting section, or only the one given with --notifier.
+Use it to verify a webhook or mail setup before relying on it.
+
+Examples:
+  gopunch alert test
+  gopunch alert test --notifier pagerduty`,
+	Args: cobra.NoArgs,
+	Run:  runAlertTest,
+}
+
+func init() {
+	rootCmd.AddCommand(alertCmd)
+	alertCmd.AddCommand(alertTestCmd)
+
+	alertTestCmd.Flags().StringVarP(&alertTestNotifier, "notifier", "n", "", "Only test this notifier")
+}
+
+func runAlertTest(cmd *cobra.Command, args []string) {
+	filename := cfgFile
+	if filename == "" {
+		filename = "gopunch.json"
+	}
+
+	cfg, err := LoadConfig(filename)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Alerting == nil {
+		color.Red("✗ No alerting section in %s", filename)
+		os.Exit(1)
+	}
+
+	alertConfig, err := buildAlerterConfig(cfg.Alerting)
+	if err != nil {
+		fmt.Printf("Error in alerting config: %v\n", err)
+		os.Exit(1)
+	}
+	alertSystem := alerter.New(alertConfig)
+
+	names := alertSystem.Notifiers()
+	if alertTestNotifier != "" {
+		names = []string{alertTestNotifier}
+	}
+	if len(names) == 0 {
+		color.Red("✗ No notifiers configured")
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, name := range names {
+		if err := alertSystem.SendTest(name); err != nil {
+			color.Red("✗ %s: %v", name, err)
+			failed = true
+			continue
+		}
+		color.Green("✓ %s", name)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
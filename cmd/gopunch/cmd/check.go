@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/TheRemyyy/gopunch/internal/checker"
+	"github.com/TheRemyyy/gopunch/internal/health"
 )
 
 var (
@@ -24,7 +26,31 @@ var (
 	checkRetries     int
 	checkFormat      string
 	checkQuiet       bool
+	checkVerbose     bool
 	checkConcurrency int
+	checkSend        string
+	checkMatch       string
+	checkReadTimeout int
+	checkNoReplyOK   bool
+	checkClientCert  string
+	checkClientKey   string
+	checkCACert      string
+	checkServerName  string
+	checkMinTLS      string
+	checkUser        string
+	checkBearer      string
+	checkProxy       string
+	checkNoProxy     []string
+	checkResolve     []string
+	checkConnectTo   []string
+	checkAllIPs      bool
+	checkMinHealthy  int
+	checkIPFamily    string
+	checkHTTPVersion string
+	checkMaxRedirect int
+	checkRequireTLS  bool
+	checkFinalURL    string
+	checkKeepAlive   bool
 )
 
 var checkCmd = &cobra.Command{
@@ -35,8 +61,11 @@ var checkCmd = &cobra.Command{
 Supports schemes:
   http://, https://  - Standard HTTP check
   tcp://host:port    - TCP port check
-  dns://host         - DNS resolution check
-  ssl://host:port    - SSL certificate expiry check
+  udp://host:port    - UDP probe (send/match a datagram)
+  dns://host         - DNS lookup (?type=MX&server=1.1.1.1&expect=...)
+  ssl://host:port    - TLS certificate and policy check (?min_tls=1.3)
+  grpc://host:port/service - gRPC health check (grpcs:// for TLS)
+  ws://, wss://      - WebSocket handshake and optional echo check
 
 If config file exists, defaults are loaded from it.`,
 	Args: cobra.ArbitraryArgs,
@@ -56,7 +85,31 @@ func init() {
 	checkCmd.Flags().IntVarP(&checkRetries, "retries", "r", 0, "Number of retries on failure")
 	checkCmd.Flags().StringVarP(&checkFormat, "format", "f", "table", "Output format")
 	checkCmd.Flags().BoolVarP(&checkQuiet, "quiet", "q", false, "Minimal output")
+	checkCmd.Flags().BoolVarP(&checkVerbose, "verbose", "v", false, "Show redirect chains in table output")
 	checkCmd.Flags().IntVarP(&checkConcurrency, "concurrency", "c", 10, "Max concurrent requests")
+	checkCmd.Flags().StringVar(&checkSend, "send", "", "Payload to send for ws/tcp/udp probes (hex:... for bytes)")
+	checkCmd.Flags().StringVar(&checkMatch, "match", "", "Expected reply for ws/tcp/udp probes (re:... for regex, hex:... for bytes)")
+	checkCmd.Flags().IntVar(&checkReadTimeout, "read-timeout", 0, "Seconds to wait for a probe reply (default: timeout)")
+	checkCmd.Flags().BoolVar(&checkNoReplyOK, "accept-no-reply", false, "Treat udp:// targets that don't reply as up")
+	checkCmd.Flags().StringVar(&checkClientCert, "cert", "", "Client certificate (PEM) for mutual TLS")
+	checkCmd.Flags().StringVar(&checkClientKey, "key", "", "Private key (PEM) for --cert")
+	checkCmd.Flags().StringVar(&checkCACert, "cacert", "", "CA bundle (PEM) to verify servers against")
+	checkCmd.Flags().StringVar(&checkServerName, "sni", "", "Server name to send and verify instead of the target host")
+	checkCmd.Flags().StringVar(&checkMinTLS, "min-tls", "", "Minimum TLS version to negotiate (1.0-1.3)")
+	checkCmd.Flags().StringVarP(&checkUser, "user", "u", "", "Basic auth credentials as user:password")
+	checkCmd.Flags().StringVar(&checkBearer, "bearer", "", "Bearer token for HTTP checks")
+	checkCmd.Flags().StringVar(&checkProxy, "proxy", "", "Proxy for HTTP, TCP and SSL checks (http://, https:// or socks5://)")
+	checkCmd.Flags().StringSliceVar(&checkNoProxy, "no-proxy", nil, "Hosts, domains or CIDRs that bypass the proxy")
+	checkCmd.Flags().StringArrayVar(&checkResolve, "resolve", nil, "Connect to an address for host:port (host:port:address)")
+	checkCmd.Flags().StringArrayVar(&checkConnectTo, "connect-to", nil, "Connect to another host and port (host:port:connect-host:connect-port)")
+	checkCmd.Flags().BoolVar(&checkAllIPs, "all-ips", false, "Check every resolved IP of http(s), tcp and ssl targets")
+	checkCmd.Flags().IntVar(&checkMinHealthy, "min-healthy", 0, "IPs that must be up with --all-ips (default: all)")
+	checkCmd.Flags().StringVar(&checkIPFamily, "ip-family", "", "Connect over IPv4 (4), IPv6 (6) or check both separately (both)")
+	checkCmd.Flags().StringVar(&checkHTTPVersion, "http-version", "auto", "HTTP version to require: auto, 1.1, 2 or 3 (QUIC)")
+	checkCmd.Flags().IntVar(&checkMaxRedirect, "max-redirects", 0, "Fail after this many redirects (default 10)")
+	checkCmd.Flags().BoolVar(&checkRequireTLS, "require-https", false, "Fail unless redirects stay on https and end there")
+	checkCmd.Flags().StringVar(&checkFinalURL, "final-url", "", "URL the redirect chain must end at")
+	checkCmd.Flags().BoolVar(&checkKeepAlive, "keep-alive", false, "Reuse HTTP connections between checks of a target (warm probes)")
 }
 
 func runCheck(cmd *cobra.Command, args []string) {
@@ -80,9 +133,6 @@ func runCheck(cmd *cobra.Command, args []string) {
 
 	// Apply config defaults
 	if cfg != nil {
-		if len(args) == 0 {
-			args = cfg.URLs
-		}
 		if !cmd.Flags().Changed("timeout") && cfg.Timeout > 0 {
 			checkTimeout = cfg.Timeout
 		}
@@ -106,6 +156,69 @@ func runCheck(cmd *cobra.Command, args []string) {
 		if !cmd.Flags().Changed("expect") && len(cfg.ExpectedCodes) > 0 {
 			checkExpect = cfg.ExpectedCodes
 		}
+		if !cmd.Flags().Changed("send") && cfg.Send != "" {
+			checkSend = cfg.Send
+		}
+		if !cmd.Flags().Changed("match") && cfg.Match != "" {
+			checkMatch = cfg.Match
+		}
+		if !cmd.Flags().Changed("read-timeout") && cfg.ReadTimeout > 0 {
+			checkReadTimeout = cfg.ReadTimeout
+		}
+		if !cmd.Flags().Changed("accept-no-reply") {
+			checkNoReplyOK = cfg.AcceptNoReply
+		}
+		if !cmd.Flags().Changed("cert") && cfg.ClientCert != "" {
+			checkClientCert = cfg.ClientCert
+		}
+		if !cmd.Flags().Changed("key") && cfg.ClientKey != "" {
+			checkClientKey = cfg.ClientKey
+		}
+		if !cmd.Flags().Changed("cacert") && cfg.CACert != "" {
+			checkCACert = cfg.CACert
+		}
+		if !cmd.Flags().Changed("sni") && cfg.ServerName != "" {
+			checkServerName = cfg.ServerName
+		}
+		if !cmd.Flags().Changed("min-tls") && cfg.MinTLS != "" {
+			checkMinTLS = cfg.MinTLS
+		}
+		if !cmd.Flags().Changed("proxy") && cfg.Proxy != "" {
+			checkProxy = cfg.Proxy
+		}
+		if !cmd.Flags().Changed("no-proxy") && cfg.NoProxy != nil {
+			checkNoProxy = cfg.NoProxy
+		}
+		if !cmd.Flags().Changed("resolve") && cfg.Resolve != nil {
+			checkResolve = cfg.Resolve
+		}
+		if !cmd.Flags().Changed("connect-to") && cfg.ConnectTo != nil {
+			checkConnectTo = cfg.ConnectTo
+		}
+		if !cmd.Flags().Changed("all-ips") {
+			checkAllIPs = cfg.AllIPs
+		}
+		if !cmd.Flags().Changed("min-healthy") && cfg.MinHealthy > 0 {
+			checkMinHealthy = cfg.MinHealthy
+		}
+		if !cmd.Flags().Changed("ip-family") && cfg.IPFamily != "" {
+			checkIPFamily = cfg.IPFamily
+		}
+		if !cmd.Flags().Changed("http-version") && cfg.HTTPVersion != "" {
+			checkHTTPVersion = cfg.HTTPVersion
+		}
+		if !cmd.Flags().Changed("max-redirects") && cfg.MaxRedirects > 0 {
+			checkMaxRedirect = cfg.MaxRedirects
+		}
+		if !cmd.Flags().Changed("require-https") {
+			checkRequireTLS = cfg.RequireHTTPS
+		}
+		if !cmd.Flags().Changed("final-url") && cfg.FinalURL != "" {
+			checkFinalURL = cfg.FinalURL
+		}
+		if !cmd.Flags().Changed("keep-alive") {
+			checkKeepAlive = cfg.KeepAlive
+		}
 		if !cmd.Flags().Changed("retries") && cfg.Retries > 0 {
 			checkRetries = cfg.Retries
 		}
@@ -119,10 +232,33 @@ func runCheck(cmd *cobra.Command, args []string) {
 		Insecure:        checkInsecure,
 		FollowRedirects: checkFollowRedir,
 		ExpectedCodes:   checkExpect,
+		Send:            checkSend,
+		Match:           checkMatch,
+		ReadTimeout:     time.Duration(checkReadTimeout) * time.Second,
+		AcceptNoReply:   checkNoReplyOK,
+		ClientCert:      checkClientCert,
+		ClientKey:       checkClientKey,
+		CACert:          checkCACert,
+		ServerName:      checkServerName,
+		MinTLSVersion:   checkMinTLS,
+		Auth:            parseAuth(checkUser, checkBearer, cfg),
+		Proxy:           checkProxy,
+		NoProxy:         checkNoProxy,
+		Resolve:         checkResolve,
+		ConnectTo:       checkConnectTo,
+		AllIPs:          checkAllIPs,
+		MinHealthy:      checkMinHealthy,
+		IPFamily:        checkIPFamily,
+		HTTPVersion:     checkHTTPVersion,
+		MaxRedirects:    checkMaxRedirect,
+		RequireHTTPS:    checkRequireTLS,
+		FinalURL:        checkFinalURL,
+		KeepAlive:       checkKeepAlive,
 		Retries:         checkRetries,
 	}
 
-	results := checker.CheckURLs(args, opts, checkConcurrency)
+	targets := buildTargets(args, cfg, opts)
+	results := checker.CheckTargets(targets, checkConcurrency)
 
 	if checkQuiet {
 		for _, r := range results {
@@ -169,8 +305,9 @@ func printTable(results []checker.Result) {
 	// SetHeaderLine not available in v0.0.5, using default
 	table.SetTablePadding("  ")
 	table.SetNoWhiteSpace(true)
+	table.SetAutoWrapText(false)
 
-	for _, r := range results {
+	appendRow := func(r checker.Result, target string) {
 		var status, statusColor string
 		if r.Error != nil {
 			status = "✗"
@@ -192,22 +329,52 @@ func printTable(results []checker.Result) {
 			code = r.Info
 		}
 
-		if r.Size > 0 {
+		if len(r.Timings) > 0 {
+			note = formatTimings(r.Timings)
+		} else if r.SSL != nil && r.SSL.Version != "" {
+			note = fmt.Sprintf("%s, %s %d", r.SSL.Version, r.SSL.KeyType, r.SSL.KeyBits)
+		} else if len(r.Answers) > 0 {
+			note = truncate(strings.Join(r.Answers, ", "), 60)
+		} else if r.Size > 0 {
 			note = formatBytes(r.Size)
 		} else if r.Retries > 0 {
 			note = fmt.Sprintf("%d retries", r.Retries)
 		}
+		// HTTP/1.x is the norm; newer protocols are worth pointing out
+		if r.Protocol != "" && !strings.HasPrefix(r.Protocol, "HTTP/1.") {
+			note = strings.TrimSuffix(r.Protocol+", "+note, ", ")
+		}
 
 		timeStr := fmt.Sprintf("%dms", r.Duration.Milliseconds())
 
 		table.Append([]string{
 			statusColor,
-			cyan.Sprint(r.URL),
+			cyan.Sprint(target),
 			code,
 			timeStr,
 			note,
 		})
 	}
+
+	for _, r := range results {
+		appendRow(r, r.URL)
+		// One indented row per family for --ip-family both, and per
+		// address for --all-ips
+		for _, f := range r.Families {
+			appendRow(f, "  └ "+f.Family)
+			for _, a := range f.Addresses {
+				appendRow(a, "      └ "+a.Address)
+			}
+		}
+		for _, a := range r.Addresses {
+			appendRow(a, "  └ "+a.Address)
+		}
+		if checkVerbose {
+			for _, h := range r.Redirects {
+				table.Append([]string{"", "  → " + h.URL, fmt.Sprintf("%d", h.StatusCode), fmt.Sprintf("%dms", h.Duration.Milliseconds()), ""})
+			}
+		}
+	}
 	table.Render()
 	fmt.Println()
 }
@@ -217,15 +384,48 @@ func printJSON(results []checker.Result) {
 	for i, r := range results {
 		errStr := "null"
 		if r.Error != nil {
-			errStr = fmt.Sprintf("\"%s\"", r.Error.Error())
+			quoted, _ := json.Marshal(r.Error.Error())
+			errStr = string(quoted)
 		}
 		info := r.Info
 		if info == "" && r.StatusCode > 0 {
 			info = fmt.Sprintf("%d", r.StatusCode)
 		}
 
-		fmt.Printf(`  {"url":"%s","info":"%s","duration_ms":%d,"size":%d,"success":%t,"error":%s}`,
-			r.URL, info, r.Duration.Milliseconds(), r.Size, r.Success, errStr)
+		extra := ""
+		if len(r.Timings) > 0 {
+			timings, _ := json.Marshal(newTimingReports(r.Timings))
+			extra = fmt.Sprintf(`,"timings":%s`, timings)
+		}
+		if len(r.Answers) > 0 {
+			answers, _ := json.Marshal(r.Answers)
+			extra += fmt.Sprintf(`,"answers":%s`, answers)
+		}
+		if r.SSL != nil && r.SSL.Version != "" {
+			tlsInfo, _ := json.Marshal(newTLSReport(r.SSL))
+			extra += fmt.Sprintf(`,"tls":%s`, tlsInfo)
+		}
+		if r.Protocol != "" {
+			extra += fmt.Sprintf(`,"protocol":"%s"`, r.Protocol)
+		}
+		if r.Reused {
+			extra += `,"reused":true`
+		}
+		if len(r.Redirects) > 0 {
+			redirects, _ := json.Marshal(newRedirectReports(r.Redirects))
+			extra += fmt.Sprintf(`,"redirects":%s`, redirects)
+		}
+		if len(r.Families) > 0 {
+			families, _ := json.Marshal(newSubReports(r.Families))
+			extra += fmt.Sprintf(`,"families":%s`, families)
+		}
+		if len(r.Addresses) > 0 {
+			addresses, _ := json.Marshal(newSubReports(r.Addresses))
+			extra += fmt.Sprintf(`,"addresses":%s`, addresses)
+		}
+
+		fmt.Printf(`  {"url":"%s","info":"%s","duration_ms":%d,"size":%d,"success":%t,"error":%s%s}`,
+			r.URL, info, r.Duration.Milliseconds(), r.Size, r.Success, errStr, extra)
 		if i < len(results)-1 {
 			fmt.Println(",")
 		} else {
@@ -235,6 +435,126 @@ func printJSON(results []checker.Result) {
 	fmt.Println("]")
 }
 
+// timingReport is the JSON form of one phase, step or resolver timing. A
+// list keeps them in order, and names are free-form so they can repeat.
+type timingReport struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func newTimingReports(timings []checker.Timing) []timingReport {
+	reports := make([]timingReport, len(timings))
+	for i, t := range timings {
+		reports[i] = timingReport{Name: t.Name, DurationMs: t.Duration.Milliseconds()}
+	}
+	return reports
+}
+
+// redirectReport is the JSON form of one hop in a redirect chain
+type redirectReport struct {
+	URL        string `json:"url"`
+	Status     int    `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func newRedirectReports(hops []checker.Hop) []redirectReport {
+	reports := make([]redirectReport, len(hops))
+	for i, h := range hops {
+		reports[i] = redirectReport{URL: h.URL, Status: h.StatusCode, DurationMs: h.Duration.Milliseconds()}
+	}
+	return reports
+}
+
+// subReport is the JSON form of one address checked with --all-ips or one
+// family checked with --ip-family both
+type subReport struct {
+	Family     string      `json:"family,omitempty"`
+	Address    string      `json:"address,omitempty"`
+	Info       string      `json:"info"`
+	DurationMs int64       `json:"duration_ms"`
+	Success    bool        `json:"success"`
+	Error      *string     `json:"error"`
+	Addresses  []subReport `json:"addresses,omitempty"`
+}
+
+func newSubReports(results []checker.Result) []subReport {
+	reports := make([]subReport, len(results))
+	for i, r := range results {
+		reports[i] = subReport{
+			Family:     r.Family,
+			Address:    r.Address,
+			Info:       r.Info,
+			DurationMs: r.Duration.Milliseconds(),
+			Success:    r.Success,
+		}
+		if len(r.Addresses) > 0 {
+			reports[i].Addresses = newSubReports(r.Addresses)
+		}
+		if reports[i].Info == "" && r.StatusCode > 0 {
+			reports[i].Info = fmt.Sprintf("%d", r.StatusCode)
+		}
+		if r.Error != nil {
+			msg := r.Error.Error()
+			reports[i].Error = &msg
+		}
+	}
+	return reports
+}
+
+// tlsReport is the JSON form of a TLS inspection
+type tlsReport struct {
+	Version            string        `json:"version"`
+	CipherSuite        string        `json:"cipher_suite"`
+	KeyType            string        `json:"key_type"`
+	KeyBits            int           `json:"key_bits"`
+	SignatureAlgorithm string        `json:"signature_algorithm"`
+	DNSNames           []string      `json:"dns_names"`
+	HostnameMatch      bool          `json:"hostname_match"`
+	OCSPStapled        bool          `json:"ocsp_stapled"`
+	OCSPStatus         string        `json:"ocsp_status,omitempty"`
+	ChainDaysLeft      int           `json:"chain_days_left"`
+	Chain              []chainReport `json:"chain"`
+	Violations         []string      `json:"violations,omitempty"`
+}
+
+type chainReport struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysLeft           int       `json:"days_left"`
+	KeyType            string    `json:"key_type"`
+	KeyBits            int       `json:"key_bits"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+}
+
+func newTLSReport(s *health.SSLResult) tlsReport {
+	report := tlsReport{
+		Version:            s.Version,
+		CipherSuite:        s.CipherSuite,
+		KeyType:            s.KeyType,
+		KeyBits:            s.KeyBits,
+		SignatureAlgorithm: s.SignatureAlgorithm,
+		DNSNames:           s.DNSNames,
+		HostnameMatch:      s.HostnameMatch,
+		OCSPStapled:        s.OCSPStapled,
+		OCSPStatus:         s.OCSPStatus,
+		ChainDaysLeft:      s.ChainDaysLeft,
+		Violations:         s.Violations,
+	}
+	for _, c := range s.Chain {
+		report.Chain = append(report.Chain, chainReport{
+			Subject:            c.Subject,
+			Issuer:             c.Issuer,
+			NotAfter:           c.NotAfter,
+			DaysLeft:           c.DaysLeft,
+			KeyType:            c.KeyType,
+			KeyBits:            c.KeyBits,
+			SignatureAlgorithm: c.SignatureAlgorithm,
+		})
+	}
+	return report
+}
+
 func printCSV(results []checker.Result) {
 	fmt.Println("url,info,duration_ms,size,success,error")
 	for _, r := range results {
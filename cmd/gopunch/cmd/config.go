@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/TheRemyyy/gopunch/internal/alerter"
+	"github.com/TheRemyyy/gopunch/internal/checker"
 )
 
 type Config struct {
@@ -18,13 +22,263 @@ type Config struct {
 	Concurrency   int               `json:"concurrency"`
 	Retries       int               `json:"retries"`
 	ExpectedCodes []int             `json:"expected_codes,omitempty"`
+	Send          string            `json:"send,omitempty"`
+	Match         string            `json:"match,omitempty"`
+	ReadTimeout   int               `json:"read_timeout,omitempty"`
+	AcceptNoReply bool              `json:"accept_no_reply,omitempty"`
+	ClientCert    string            `json:"client_cert,omitempty"`
+	ClientKey     string            `json:"client_key,omitempty"`
+	CACert        string            `json:"ca_cert,omitempty"`
+	ServerName    string            `json:"server_name,omitempty"`
+	MinTLS        string            `json:"min_tls,omitempty"`
+	Auth          *AuthConfig       `json:"auth,omitempty"`
+	Proxy         string            `json:"proxy,omitempty"`
+	NoProxy       []string          `json:"no_proxy,omitempty"`
+	Resolve       []string          `json:"resolve,omitempty"`
+	ConnectTo     []string          `json:"connect_to,omitempty"`
+	AllIPs        bool              `json:"all_ips,omitempty"`
+	MinHealthy    int               `json:"min_healthy,omitempty"`
+	IPFamily      string            `json:"ip_family,omitempty"`
+	HTTPVersion   string            `json:"http_version,omitempty"`
+	MaxRedirects  int               `json:"max_redirects,omitempty"`
+	RequireHTTPS  bool              `json:"require_https,omitempty"`
+	FinalURL      string            `json:"final_url,omitempty"`
+	KeepAlive     bool              `json:"keep_alive,omitempty"`
+	Targets       []TargetConfig    `json:"targets,omitempty"`
 	Alerting      *AlertConfig      `json:"alerting,omitempty"`
 }
 
+// TargetConfig is a target with settings that override the global ones
+type TargetConfig struct {
+	URL           string       `json:"url"`
+	Send          string       `json:"send,omitempty"`
+	Match         string       `json:"match,omitempty"`
+	ReadTimeout   int          `json:"read_timeout,omitempty"`
+	AcceptNoReply *bool        `json:"accept_no_reply,omitempty"`
+	ClientCert    string       `json:"client_cert,omitempty"`
+	ClientKey     string       `json:"client_key,omitempty"`
+	CACert        string       `json:"ca_cert,omitempty"`
+	ServerName    string       `json:"server_name,omitempty"`
+	MinTLS        string       `json:"min_tls,omitempty"`
+	Auth          *AuthConfig  `json:"auth,omitempty"`
+	Steps         []StepConfig `json:"steps,omitempty"`
+	Proxy         string       `json:"proxy,omitempty"`
+	NoProxy       []string     `json:"no_proxy,omitempty"`
+	Resolve       []string     `json:"resolve,omitempty"`
+	ConnectTo     []string     `json:"connect_to,omitempty"`
+	AllIPs        *bool        `json:"all_ips,omitempty"`
+	MinHealthy    int          `json:"min_healthy,omitempty"`
+	IPFamily      string       `json:"ip_family,omitempty"`
+	HTTPVersion   string       `json:"http_version,omitempty"`
+	MaxRedirects  int          `json:"max_redirects,omitempty"`
+	RequireHTTPS  *bool        `json:"require_https,omitempty"`
+	FinalURL      string       `json:"final_url,omitempty"`
+	KeepAlive     *bool        `json:"keep_alive,omitempty"`
+}
+
+// StepConfig is one request of a multi-step check
+type StepConfig struct {
+	Name    string            `json:"name,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Expect  []int             `json:"expect,omitempty"`
+	Match   string            `json:"match,omitempty"`
+	Extract map[string]string `json:"extract,omitempty"`
+}
+
+// AuthConfig holds credentials for HTTP checks. Secrets may reference
+// environment variables as ${NAME}.
+type AuthConfig struct {
+	Type         string   `json:"type"` // basic, bearer, oauth2
+	Username     string   `json:"username,omitempty"`
+	Password     string   `json:"password,omitempty"`
+	Token        string   `json:"token,omitempty"`
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+func (a *AuthConfig) toChecker() *checker.Auth {
+	if a == nil {
+		return nil
+	}
+	return &checker.Auth{
+		Type:         a.Type,
+		Username:     os.ExpandEnv(a.Username),
+		Password:     os.ExpandEnv(a.Password),
+		Token:        os.ExpandEnv(a.Token),
+		TokenURL:     a.TokenURL,
+		ClientID:     os.ExpandEnv(a.ClientID),
+		ClientSecret: os.ExpandEnv(a.ClientSecret),
+		Scopes:       a.Scopes,
+	}
+}
+
+// options returns the global options with this target's overrides applied
+func (t TargetConfig) options(base checker.Options) checker.Options {
+	opts := base
+	if t.Send != "" {
+		opts.Send = t.Send
+	}
+	if t.Match != "" {
+		opts.Match = t.Match
+	}
+	if t.ReadTimeout > 0 {
+		opts.ReadTimeout = time.Duration(t.ReadTimeout) * time.Second
+	}
+	if t.AcceptNoReply != nil {
+		opts.AcceptNoReply = *t.AcceptNoReply
+	}
+	if t.ClientCert != "" {
+		opts.ClientCert = t.ClientCert
+		opts.ClientKey = t.ClientKey
+	}
+	if t.CACert != "" {
+		opts.CACert = t.CACert
+	}
+	if t.ServerName != "" {
+		opts.ServerName = t.ServerName
+	}
+	if t.MinTLS != "" {
+		opts.MinTLSVersion = t.MinTLS
+	}
+	if t.Auth != nil {
+		opts.Auth = t.Auth.toChecker()
+	}
+	if t.Proxy != "" {
+		opts.Proxy = t.Proxy
+	}
+	if t.NoProxy != nil {
+		opts.NoProxy = t.NoProxy
+	}
+	if t.Resolve != nil {
+		opts.Resolve = t.Resolve
+	}
+	if t.ConnectTo != nil {
+		opts.ConnectTo = t.ConnectTo
+	}
+	if t.AllIPs != nil {
+		opts.AllIPs = *t.AllIPs
+	}
+	if t.MinHealthy > 0 {
+		opts.MinHealthy = t.MinHealthy
+	}
+	if t.IPFamily != "" {
+		opts.IPFamily = t.IPFamily
+	}
+	if t.HTTPVersion != "" {
+		opts.HTTPVersion = t.HTTPVersion
+	}
+	if t.MaxRedirects > 0 {
+		opts.MaxRedirects = t.MaxRedirects
+	}
+	if t.RequireHTTPS != nil {
+		opts.RequireHTTPS = *t.RequireHTTPS
+	}
+	if t.FinalURL != "" {
+		opts.FinalURL = t.FinalURL
+	}
+	if t.KeepAlive != nil {
+		opts.KeepAlive = *t.KeepAlive
+	}
+	for _, step := range t.Steps {
+		opts.Steps = append(opts.Steps, checker.Step{
+			Name:          step.Name,
+			Method:        step.Method,
+			URL:           step.URL,
+			Headers:       step.Headers,
+			Body:          step.Body,
+			ExpectedCodes: step.Expect,
+			Match:         step.Match,
+			Extract:       step.Extract,
+		})
+	}
+	return opts
+}
+
+// buildTargets pairs each URL with its options. URLs given on the command
+// line use the global options; otherwise the config's urls and targets are
+// checked.
+func buildTargets(args []string, cfg *Config, opts checker.Options) []checker.Target {
+	var targets []checker.Target
+	if len(args) > 0 || cfg == nil {
+		for _, u := range args {
+			targets = append(targets, checker.Target{URL: u, Options: opts})
+		}
+		return targets
+	}
+
+	for _, u := range cfg.URLs {
+		targets = append(targets, checker.Target{URL: u, Options: opts})
+	}
+	for _, t := range cfg.Targets {
+		targets = append(targets, checker.Target{URL: t.URL, Options: t.options(opts)})
+	}
+	return targets
+}
+
 type AlertConfig struct {
-	Enabled  bool           `json:"enabled"`
-	Cooldown int            `json:"cooldown_seconds"`
-	Webhook  *WebhookConfig `json:"webhook,omitempty"`
+	Enabled      bool                      `json:"enabled"`
+	Cooldown     int                       `json:"cooldown_seconds"`
+	Webhook      *WebhookConfig            `json:"webhook,omitempty"`
+	Dependencies []DependencyConfig        `json:"dependencies,omitempty"`
+	Notifiers    map[string]NotifierConfig `json:"notifiers,omitempty"`
+	Routes       []RouteConfig             `json:"routes,omitempty"`
+	GroupWindow  int                       `json:"group_window_seconds,omitempty"`
+	CertExpiry   *CertExpiryConfig         `json:"cert_expiry,omitempty"`
+	LatencyRules []LatencyRuleConfig       `json:"latency_rules,omitempty"`
+	HistoryFile  string                    `json:"history_file,omitempty"`
+}
+
+type LatencyRuleConfig struct {
+	Targets    []string `json:"targets,omitempty"`
+	Threshold  int      `json:"threshold_ms,omitempty"`
+	Percentile float64  `json:"percentile,omitempty"`
+	Window     int      `json:"window_seconds,omitempty"`
+	Baseline   int      `json:"baseline_seconds,omitempty"`
+	Factor     float64  `json:"factor,omitempty"`
+	Cooldown   int      `json:"cooldown_seconds"`
+}
+
+type CertExpiryConfig struct {
+	WarningDays  int `json:"warning_days"`
+	CriticalDays int `json:"critical_days"`
+}
+
+type NotifierConfig struct {
+	Type       string   `json:"type"` // webhook, email, pagerduty, command
+	URL        string   `json:"url,omitempty"`
+	Method     string   `json:"method,omitempty"`
+	SMTPHost   string   `json:"smtp_host,omitempty"`
+	SMTPPort   int      `json:"smtp_port,omitempty"`
+	Username   string   `json:"username,omitempty"`
+	Password   string   `json:"password,omitempty"`
+	From       string   `json:"from,omitempty"`
+	To         []string `json:"to,omitempty"`
+	RoutingKey string   `json:"routing_key,omitempty"`
+	Severity   string   `json:"severity,omitempty"`
+	Command    string   `json:"command,omitempty"`
+	Args       []string `json:"args,omitempty"`
+	Timeout    int      `json:"timeout_seconds,omitempty"`
+}
+
+type RouteConfig struct {
+	Targets []string               `json:"targets,omitempty"`
+	Repeat  int                    `json:"repeat_seconds"`
+	Steps   []EscalationStepConfig `json:"steps"`
+}
+
+type EscalationStepConfig struct {
+	After     int      `json:"after_seconds"`
+	Notifiers []string `json:"notifiers"`
+}
+
+type DependencyConfig struct {
+	Targets   []string `json:"targets"`
+	DependsOn []string `json:"depends_on"`
 }
 
 type WebhookConfig struct {
@@ -47,6 +301,113 @@ func LoadConfig(filename string) (*Config, error) {
 	return &cfg, nil
 }
 
+// buildAlerterConfig converts the alerting section into alerter.Config
+func buildAlerterConfig(ac *AlertConfig) (alerter.Config, error) {
+	cfg := alerter.Config{
+		Enabled:     ac.Enabled,
+		Cooldown:    time.Duration(ac.Cooldown) * time.Second,
+		GroupWindow: time.Duration(ac.GroupWindow) * time.Second,
+	}
+
+	if ac.Webhook != nil {
+		cfg.Webhook = &alerter.WebhookConfig{URL: ac.Webhook.URL, Method: ac.Webhook.Method}
+	}
+
+	if ac.HistoryFile != "" {
+		f, err := os.OpenFile(ac.HistoryFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to open alert history: %w", err)
+		}
+		cfg.History = f
+	}
+
+	if ac.CertExpiry != nil {
+		cfg.CertExpiry = &alerter.CertExpiryConfig{
+			WarningDays:  ac.CertExpiry.WarningDays,
+			CriticalDays: ac.CertExpiry.CriticalDays,
+		}
+	}
+
+	for _, l := range ac.LatencyRules {
+		cfg.LatencyRules = append(cfg.LatencyRules, alerter.LatencyRule{
+			Targets:    l.Targets,
+			Threshold:  time.Duration(l.Threshold) * time.Millisecond,
+			Percentile: l.Percentile,
+			Window:     time.Duration(l.Window) * time.Second,
+			Baseline:   time.Duration(l.Baseline) * time.Second,
+			Factor:     l.Factor,
+			Cooldown:   time.Duration(l.Cooldown) * time.Second,
+		})
+	}
+
+	for _, d := range ac.Dependencies {
+		cfg.Dependencies = append(cfg.Dependencies, alerter.Dependency{Targets: d.Targets, DependsOn: d.DependsOn})
+	}
+
+	if len(ac.Notifiers) > 0 {
+		cfg.Notifiers = make(map[string]alerter.NotifierConfig)
+	}
+	for name, n := range ac.Notifiers {
+		var nc alerter.NotifierConfig
+		switch n.Type {
+		case "webhook", "discord", "slack":
+			nc.Webhook = &alerter.WebhookConfig{URL: n.URL, Method: n.Method}
+		case "email":
+			nc.Email = &alerter.EmailConfig{
+				Host:     n.SMTPHost,
+				Port:     n.SMTPPort,
+				Username: n.Username,
+				Password: n.Password,
+				From:     n.From,
+				To:       n.To,
+			}
+		case "pagerduty":
+			nc.PagerDuty = &alerter.PagerDutyConfig{RoutingKey: n.RoutingKey, Severity: n.Severity}
+		case "command":
+			nc.Command = &alerter.CommandConfig{
+				Command: n.Command,
+				Args:    n.Args,
+				Timeout: time.Duration(n.Timeout) * time.Second,
+			}
+		default:
+			return cfg, fmt.Errorf("notifier %q has unknown type %q", name, n.Type)
+		}
+		cfg.Notifiers[name] = nc
+	}
+
+	for _, r := range ac.Routes {
+		route := alerter.Route{
+			Targets:        r.Targets,
+			RepeatInterval: time.Duration(r.Repeat) * time.Second,
+		}
+		for _, step := range r.Steps {
+			route.Steps = append(route.Steps, alerter.EscalationStep{
+				After:     time.Duration(step.After) * time.Second,
+				Notifiers: step.Notifiers,
+			})
+		}
+		cfg.Routes = append(cfg.Routes, route)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// parseAuth builds auth from the --user and --bearer flags, falling back to
+// the config's auth section
+func parseAuth(user, bearer string, cfg *Config) *checker.Auth {
+	if user != "" {
+		username, password, _ := strings.Cut(user, ":")
+		return &checker.Auth{Type: "basic", Username: username, Password: password}
+	}
+	if bearer != "" {
+		return &checker.Auth{Type: "bearer", Token: bearer}
+	}
+	if cfg != nil {
+		return cfg.Auth.toChecker()
+	}
+	return nil
+}
+
 func parseHeaders(headers []string) map[string]string {
 	result := make(map[string]string)
 	for _, h := range headers {
@@ -58,6 +419,21 @@ func parseHeaders(headers []string) map[string]string {
 	return result
 }
 
+func formatTimings(timings []checker.Timing) string {
+	parts := make([]string, len(timings))
+	for i, t := range timings {
+		parts[i] = fmt.Sprintf("%s %dms", t.Name, t.Duration.Milliseconds())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
+
 func formatBytes(b int64) string {
 	if b < 0 {
 		return "-"
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/TheRemyyy/gopunch/internal/health"
+)
+
+var (
+	dnsServers []string
+	dnsType    string
+	dnsTimeout int
+)
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "DNS lookup tools",
+}
+
+var dnsCompareCmd = &cobra.Command{
+	Use:   "compare <name>",
+	Short: "Compare the answers several nameservers give for a name",
+	Long: `Query the same name against several nameservers at once and flag any
+disagreement between their answers. Useful during DNS migrations to compare
+authoritative and recursive resolvers, or a primary and its secondaries.
+
+Exits with status 1 if a lookup fails or the answers differ.
+
+Examples:
+  gopunch dns compare example.com -s 1.1.1.1 -s 8.8.8.8 -s ns1.example.com
+  gopunch dns compare example.com --type MX --server 1.1.1.1,8.8.8.8`,
+	Args: cobra.ExactArgs(1),
+	Run:  runDNSCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(dnsCmd)
+	dnsCmd.AddCommand(dnsCompareCmd)
+
+	dnsCompareCmd.Flags().StringSliceVarP(&dnsServers, "server", "s", nil, "Nameserver to query (repeat or comma-separate)")
+	dnsCompareCmd.Flags().StringVarP(&dnsType, "type", "T", "A", "Record type (A, AAAA, CNAME, MX, TXT, NS, SRV, CAA)")
+	dnsCompareCmd.Flags().IntVarP(&dnsTimeout, "timeout", "t", 5, "Lookup timeout in seconds")
+}
+
+func runDNSCompare(cmd *cobra.Command, args []string) {
+	if len(dnsServers) < 2 {
+		color.Red("✗ Give at least two nameservers with --server")
+		os.Exit(1)
+	}
+
+	cmp := health.CompareDNS(args[0], dnsServers, health.DNSOptions{
+		Type:    dnsType,
+		Timeout: time.Duration(dnsTimeout) * time.Second,
+	})
+
+	green := color.New(color.FgGreen, color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+	yellow := color.New(color.FgYellow)
+	cyan := color.New(color.FgCyan)
+
+	// Servers outside the largest group are the ones that disagree
+	majority := make(map[string]bool)
+	largest := -1
+	for i, g := range cmp.Groups {
+		if largest < 0 || len(g) > len(cmp.Groups[largest]) {
+			largest = i
+		}
+	}
+	if largest >= 0 {
+		for _, server := range cmp.Groups[largest] {
+			majority[server] = true
+		}
+	}
+
+	fmt.Println()
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Status", "Server", "Time", "Answers"})
+	table.SetBorder(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetTablePadding("  ")
+	table.SetNoWhiteSpace(true)
+
+	for _, res := range cmp.Results {
+		status := green.Sprint("✓")
+		answers := strings.Join(res.Answers, ", ")
+		if res.Error != nil && len(res.Answers) == 0 {
+			status = red.Sprint("✗")
+			answers = res.Error.Error()
+		} else if !majority[res.Server] {
+			status = yellow.Sprint("!")
+		}
+
+		table.Append([]string{
+			status,
+			cyan.Sprint(res.Server),
+			fmt.Sprintf("%dms", res.Duration.Milliseconds()),
+			truncate(answers, 80),
+		})
+	}
+	table.Render()
+	fmt.Println()
+
+	if cmp.Consistent {
+		color.Green("✓ %d nameservers agree on %s %s", len(dnsServers), cmp.Type, args[0])
+		return
+	}
+	if len(cmp.Groups) > 1 {
+		color.Red("✗ %d different answer sets for %s %s", len(cmp.Groups), cmp.Type, args[0])
+	} else {
+		color.Red("✗ Lookup failed for %s %s", cmp.Type, args[0])
+	}
+	os.Exit(1)
+}
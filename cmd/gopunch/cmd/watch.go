@@ -14,6 +14,7 @@ import (
 
 	"github.com/TheRemyyy/gopunch/internal/alerter"
 	"github.com/TheRemyyy/gopunch/internal/checker"
+	"github.com/TheRemyyy/gopunch/internal/health"
 )
 
 var (
@@ -26,6 +27,29 @@ var (
 	watchFollowRedir bool
 	watchExpect      []int
 	watchConcurrency int
+	watchSend        string
+	watchMatch       string
+	watchReadTimeout int
+	watchNoReplyOK   bool
+	watchClientCert  string
+	watchClientKey   string
+	watchCACert      string
+	watchServerName  string
+	watchMinTLS      string
+	watchUser        string
+	watchBearer      string
+	watchProxy       string
+	watchNoProxy     []string
+	watchResolve     []string
+	watchConnectTo   []string
+	watchAllIPs      bool
+	watchMinHealthy  int
+	watchIPFamily    string
+	watchHTTPVersion string
+	watchMaxRedirect int
+	watchRequireTLS  bool
+	watchFinalURL    string
+	watchKeepAlive   bool
 	watchQuiet       bool
 )
 
@@ -52,6 +76,29 @@ func init() {
 	watchCmd.Flags().BoolVarP(&watchFollowRedir, "follow", "L", true, "Follow redirects")
 	watchCmd.Flags().IntSliceVarP(&watchExpect, "expect", "e", nil, "Expected status codes")
 	watchCmd.Flags().IntVarP(&watchConcurrency, "concurrency", "c", 10, "Max concurrent requests")
+	watchCmd.Flags().StringVar(&watchSend, "send", "", "Payload to send for ws/tcp/udp probes (hex:... for bytes)")
+	watchCmd.Flags().StringVar(&watchMatch, "match", "", "Expected reply for ws/tcp/udp probes (re:... for regex, hex:... for bytes)")
+	watchCmd.Flags().IntVar(&watchReadTimeout, "read-timeout", 0, "Seconds to wait for a probe reply (default: timeout)")
+	watchCmd.Flags().BoolVar(&watchNoReplyOK, "accept-no-reply", false, "Treat udp:// targets that don't reply as up")
+	watchCmd.Flags().StringVar(&watchClientCert, "cert", "", "Client certificate (PEM) for mutual TLS")
+	watchCmd.Flags().StringVar(&watchClientKey, "key", "", "Private key (PEM) for --cert")
+	watchCmd.Flags().StringVar(&watchCACert, "cacert", "", "CA bundle (PEM) to verify servers against")
+	watchCmd.Flags().StringVar(&watchServerName, "sni", "", "Server name to send and verify instead of the target host")
+	watchCmd.Flags().StringVar(&watchMinTLS, "min-tls", "", "Minimum TLS version to negotiate (1.0-1.3)")
+	watchCmd.Flags().StringVarP(&watchUser, "user", "u", "", "Basic auth credentials as user:password")
+	watchCmd.Flags().StringVar(&watchBearer, "bearer", "", "Bearer token for HTTP checks")
+	watchCmd.Flags().StringVar(&watchProxy, "proxy", "", "Proxy for HTTP, TCP and SSL checks (http://, https:// or socks5://)")
+	watchCmd.Flags().StringSliceVar(&watchNoProxy, "no-proxy", nil, "Hosts, domains or CIDRs that bypass the proxy")
+	watchCmd.Flags().StringArrayVar(&watchResolve, "resolve", nil, "Connect to an address for host:port (host:port:address)")
+	watchCmd.Flags().StringArrayVar(&watchConnectTo, "connect-to", nil, "Connect to another host and port (host:port:connect-host:connect-port)")
+	watchCmd.Flags().BoolVar(&watchAllIPs, "all-ips", false, "Check every resolved IP of http(s), tcp and ssl targets")
+	watchCmd.Flags().IntVar(&watchMinHealthy, "min-healthy", 0, "IPs that must be up with --all-ips (default: all)")
+	watchCmd.Flags().StringVar(&watchIPFamily, "ip-family", "", "Connect over IPv4 (4), IPv6 (6) or check both separately (both)")
+	watchCmd.Flags().StringVar(&watchHTTPVersion, "http-version", "auto", "HTTP version to require: auto, 1.1, 2 or 3 (QUIC)")
+	watchCmd.Flags().IntVar(&watchMaxRedirect, "max-redirects", 0, "Fail after this many redirects (default 10)")
+	watchCmd.Flags().BoolVar(&watchRequireTLS, "require-https", false, "Fail unless redirects stay on https and end there")
+	watchCmd.Flags().StringVar(&watchFinalURL, "final-url", "", "URL the redirect chain must end at")
+	watchCmd.Flags().BoolVar(&watchKeepAlive, "keep-alive", false, "Reuse HTTP connections between checks of a target (warm probes)")
 	watchCmd.Flags().BoolVarP(&watchQuiet, "quiet", "q", false, "Minimal output")
 }
 
@@ -88,12 +135,7 @@ func runWatch(cmd *cobra.Command, args []string) {
 	}
 
 	// Merge config with flags
-	urls := args
 	if cfg != nil {
-		if len(urls) == 0 {
-			urls = cfg.URLs
-		}
-
 		// Apply defaults from config if flags not changed
 		if !cmd.Flags().Changed("interval") && cfg.Interval > 0 {
 			watchInterval = cfg.Interval
@@ -121,11 +163,69 @@ func runWatch(cmd *cobra.Command, args []string) {
 		if !cmd.Flags().Changed("expect") && len(cfg.ExpectedCodes) > 0 {
 			watchExpect = cfg.ExpectedCodes
 		}
-	}
-
-	if len(urls) == 0 {
-		cmd.Help()
-		os.Exit(1)
+		if !cmd.Flags().Changed("send") && cfg.Send != "" {
+			watchSend = cfg.Send
+		}
+		if !cmd.Flags().Changed("match") && cfg.Match != "" {
+			watchMatch = cfg.Match
+		}
+		if !cmd.Flags().Changed("read-timeout") && cfg.ReadTimeout > 0 {
+			watchReadTimeout = cfg.ReadTimeout
+		}
+		if !cmd.Flags().Changed("accept-no-reply") {
+			watchNoReplyOK = cfg.AcceptNoReply
+		}
+		if !cmd.Flags().Changed("cert") && cfg.ClientCert != "" {
+			watchClientCert = cfg.ClientCert
+		}
+		if !cmd.Flags().Changed("key") && cfg.ClientKey != "" {
+			watchClientKey = cfg.ClientKey
+		}
+		if !cmd.Flags().Changed("cacert") && cfg.CACert != "" {
+			watchCACert = cfg.CACert
+		}
+		if !cmd.Flags().Changed("sni") && cfg.ServerName != "" {
+			watchServerName = cfg.ServerName
+		}
+		if !cmd.Flags().Changed("min-tls") && cfg.MinTLS != "" {
+			watchMinTLS = cfg.MinTLS
+		}
+		if !cmd.Flags().Changed("proxy") && cfg.Proxy != "" {
+			watchProxy = cfg.Proxy
+		}
+		if !cmd.Flags().Changed("no-proxy") && cfg.NoProxy != nil {
+			watchNoProxy = cfg.NoProxy
+		}
+		if !cmd.Flags().Changed("resolve") && cfg.Resolve != nil {
+			watchResolve = cfg.Resolve
+		}
+		if !cmd.Flags().Changed("connect-to") && cfg.ConnectTo != nil {
+			watchConnectTo = cfg.ConnectTo
+		}
+		if !cmd.Flags().Changed("all-ips") {
+			watchAllIPs = cfg.AllIPs
+		}
+		if !cmd.Flags().Changed("min-healthy") && cfg.MinHealthy > 0 {
+			watchMinHealthy = cfg.MinHealthy
+		}
+		if !cmd.Flags().Changed("ip-family") && cfg.IPFamily != "" {
+			watchIPFamily = cfg.IPFamily
+		}
+		if !cmd.Flags().Changed("http-version") && cfg.HTTPVersion != "" {
+			watchHTTPVersion = cfg.HTTPVersion
+		}
+		if !cmd.Flags().Changed("max-redirects") && cfg.MaxRedirects > 0 {
+			watchMaxRedirect = cfg.MaxRedirects
+		}
+		if !cmd.Flags().Changed("require-https") {
+			watchRequireTLS = cfg.RequireHTTPS
+		}
+		if !cmd.Flags().Changed("final-url") && cfg.FinalURL != "" {
+			watchFinalURL = cfg.FinalURL
+		}
+		if !cmd.Flags().Changed("keep-alive") {
+			watchKeepAlive = cfg.KeepAlive
+		}
 	}
 
 	opts := checker.Options{
@@ -136,6 +236,28 @@ func runWatch(cmd *cobra.Command, args []string) {
 		Insecure:        watchInsecure,
 		FollowRedirects: watchFollowRedir,
 		ExpectedCodes:   watchExpect,
+		Send:            watchSend,
+		Match:           watchMatch,
+		ReadTimeout:     time.Duration(watchReadTimeout) * time.Second,
+		AcceptNoReply:   watchNoReplyOK,
+		ClientCert:      watchClientCert,
+		ClientKey:       watchClientKey,
+		CACert:          watchCACert,
+		ServerName:      watchServerName,
+		MinTLSVersion:   watchMinTLS,
+		Auth:            parseAuth(watchUser, watchBearer, cfg),
+		Proxy:           watchProxy,
+		NoProxy:         watchNoProxy,
+		Resolve:         watchResolve,
+		ConnectTo:       watchConnectTo,
+		AllIPs:          watchAllIPs,
+		MinHealthy:      watchMinHealthy,
+		IPFamily:        watchIPFamily,
+		HTTPVersion:     watchHTTPVersion,
+		MaxRedirects:    watchMaxRedirect,
+		RequireHTTPS:    watchRequireTLS,
+		FinalURL:        watchFinalURL,
+		KeepAlive:       watchKeepAlive,
 		Retries:         1,
 	}
 
@@ -143,17 +265,22 @@ func runWatch(cmd *cobra.Command, args []string) {
 		opts.Retries = cfg.Retries
 	}
 
+	targets := buildTargets(args, cfg, opts)
+	if len(targets) == 0 {
+		cmd.Help()
+		os.Exit(1)
+	}
+
 	// Setup Alerter
 	var alertSystem *alerter.Alerter
 	if cfg != nil && cfg.Alerting != nil && cfg.Alerting.Enabled {
-		wc := cfg.Alerting.Webhook
-		webhookConfig := &alerter.WebhookConfig{URL: wc.URL, Method: wc.Method}
+		alertConfig, err := buildAlerterConfig(cfg.Alerting)
+		if err != nil {
+			fmt.Printf("Error in alerting config: %v\n", err)
+			os.Exit(1)
+		}
 
-		alertSystem = alerter.New(alerter.Config{
-			Enabled:  cfg.Alerting.Enabled,
-			Cooldown: time.Duration(cfg.Alerting.Cooldown) * time.Second,
-			Webhook:  webhookConfig,
-		})
+		alertSystem = alerter.New(alertConfig)
 		fmt.Println("🔔 Alerting enabled")
 	}
 
@@ -163,9 +290,9 @@ func runWatch(cmd *cobra.Command, args []string) {
 	yellow := color.New(color.FgYellow)
 
 	stats := make(map[string]*WatchStats)
-	for _, url := range urls {
-		stats[url] = &WatchStats{
-			URL:         url,
+	for _, t := range targets {
+		stats[t.URL] = &WatchStats{
+			URL:         t.URL,
 			MinTime:     time.Hour,
 			LastSuccess: true, // Assume healthy start to avoid immediate alert? Or false?
 			// Better true to avoid alerting on first run unless it fails
@@ -178,31 +305,44 @@ func runWatch(cmd *cobra.Command, args []string) {
 	ticker := time.NewTicker(time.Duration(watchInterval) * time.Second)
 	defer ticker.Stop()
 
-	cyan.Printf("\n⚡ Watching %d URL(s) every %ds (Ctrl+C to stop)\n\n", len(urls), watchInterval)
+	cyan.Printf("\n⚡ Watching %d URL(s) every %ds (Ctrl+C to stop)\n\n", len(targets), watchInterval)
 
 	// Initial check
-	runWatchCycle(urls, opts, stats, green, red, yellow, watchQuiet, watchConcurrency, alertSystem)
+	runWatchCycle(targets, stats, green, red, yellow, watchQuiet, watchConcurrency, alertSystem)
 
 	for {
 		select {
 		case <-sigChan:
 			fmt.Println()
+			if alertSystem != nil {
+				alertSystem.Flush()
+			}
 			printWatchSummary(stats)
 			return
 		case <-ticker.C:
-			runWatchCycle(urls, opts, stats, green, red, yellow, watchQuiet, watchConcurrency, alertSystem)
+			runWatchCycle(targets, stats, green, red, yellow, watchQuiet, watchConcurrency, alertSystem)
 		}
 	}
 }
 
-func runWatchCycle(urls []string, opts checker.Options, stats map[string]*WatchStats,
+func runWatchCycle(targets []checker.Target, stats map[string]*WatchStats,
 	green, red, yellow *color.Color, quiet bool, concurrency int, alert *alerter.Alerter) {
 
-	results := checker.CheckURLs(urls, opts, concurrency)
+	results := checker.CheckTargets(targets, concurrency)
 	timestamp := time.Now().Format("15:04:05")
 
-	for _, r := range results {
-		s := stats[r.URL]
+	// Record every target's state first so dependency suppression
+	// sees the whole cycle
+	if alert != nil {
+		for _, r := range results {
+			alert.UpdateState(r.URL, r.Success && r.Error == nil)
+		}
+	}
+
+	// Results are in target order; r.URL may differ from the configured
+	// URL, e.g. with https:// added to a target without a scheme
+	for i, r := range results {
+		s := stats[targets[i].URL]
 		s.Checks++
 		s.TotalTime += r.Duration
 		s.ResponseTimes = append(s.ResponseTimes, r.Duration)
@@ -214,9 +354,17 @@ func runWatchCycle(urls []string, opts checker.Options, stats map[string]*WatchS
 			s.MaxTime = r.Duration
 		}
 
+		if r.SSL != nil && alert != nil {
+			go alert.SendCertAlert(r.URL, firstExpiring(r.SSL))
+		}
+
 		if r.Success && r.Error == nil {
 			s.Successes++
 
+			if alert != nil {
+				go alert.RecordLatency(r.URL, r.Duration)
+			}
+
 			// Recovery alert
 			if !s.LastSuccess && alert != nil {
 				go alert.SendRecoveryAlert(r.URL)
@@ -312,3 +460,27 @@ func printWatchSummary(stats map[string]*WatchStats) {
 	table.Render()
 	fmt.Println()
 }
+
+// firstExpiring returns the certificate in the chain that expires first,
+// since an intermediate can expire before the leaf
+func firstExpiring(s *health.SSLResult) alerter.CertInfo {
+	cert := alerter.CertInfo{
+		Serial:   s.Serial,
+		Issuer:   s.Issuer,
+		Subject:  s.Subject,
+		NotAfter: s.NotAfter,
+		DaysLeft: s.DaysLeft,
+	}
+	for _, c := range s.Chain {
+		if c.NotAfter.Before(cert.NotAfter) {
+			cert = alerter.CertInfo{
+				Serial:   c.Serial,
+				Issuer:   c.Issuer,
+				Subject:  c.Subject,
+				NotAfter: c.NotAfter,
+				DaysLeft: c.DaysLeft,
+			}
+		}
+	}
+	return cert
+}
@@ -1,19 +1,115 @@
 package alerter
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 // Config holds alerting configuration
 type Config struct {
-	Enabled  bool
-	Cooldown time.Duration
-	Webhook  *WebhookConfig
+	Enabled      bool
+	Cooldown     time.Duration
+	Webhook      *WebhookConfig
+	Dependencies []Dependency
+	Notifiers    map[string]NotifierConfig
+	Routes       []Route
+	GroupWindow  time.Duration // Batch alerts raised within this window into one digest
+	CertExpiry   *CertExpiryConfig
+	LatencyRules []LatencyRule
+	History      io.Writer // Receives a JSON line for every alert decision
+}
+
+// CertExpiryConfig sets how many days before expiry a certificate raises a
+// warning or critical alert. A zero threshold is disabled.
+type CertExpiryConfig struct {
+	WarningDays  int
+	CriticalDays int
+}
+
+// Threshold alert severities
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// CertInfo describes a certificate that is approaching expiry
+type CertInfo struct {
+	Serial   string
+	Issuer   string
+	Subject  string
+	NotAfter time.Time
+	DaysLeft int
+}
+
+// Route sends alerts for matching targets through a series of escalation
+// steps. Reminders repeat the current step every RepeatInterval while the
+// incident stays open (Cooldown is used when it is zero).
+type Route struct {
+	Targets        []string
+	Steps          []EscalationStep
+	RepeatInterval time.Duration
+}
+
+// EscalationStep notifies the given notifiers once an incident has been open
+// for at least After.
+type EscalationStep struct {
+	After     time.Duration
+	Notifiers []string
+}
+
+// defaultNotifier is the name under which the top-level Webhook is registered
+const defaultNotifier = "webhook"
+
+// Validate checks that notifiers are usable and that routes only reference
+// notifiers that exist.
+func (c Config) Validate() error {
+	names := map[string]bool{}
+	if c.Webhook != nil {
+		names[defaultNotifier] = true
+	}
+	for name, nc := range c.Notifiers {
+		if _, err := newNotifier(nc, nil); err != nil {
+			return fmt.Errorf("notifier %q: %w", name, err)
+		}
+		names[name] = true
+	}
+	for i, r := range c.Routes {
+		if len(r.Steps) == 0 {
+			return fmt.Errorf("route %d has no escalation steps", i+1)
+		}
+		for j, step := range r.Steps {
+			// escalate stops at the first step that isn't due yet
+			if j > 0 && step.After < r.Steps[j-1].After {
+				return fmt.Errorf("route %d: escalation steps must be in order of after_seconds", i+1)
+			}
+			for _, n := range step.Notifiers {
+				if !names[n] {
+					return fmt.Errorf("route %d references unknown notifier %q", i+1, n)
+				}
+			}
+		}
+	}
+	for i, l := range c.LatencyRules {
+		if l.Threshold <= 0 && (l.Factor <= 0 || l.Baseline <= 0) {
+			return fmt.Errorf("latency rule %d needs a threshold or a baseline and factor", i+1)
+		}
+	}
+	return nil
+}
+
+// Dependency declares that targets matching any of Targets depend on the
+// targets listed in DependsOn. Patterns may use "*" as a wildcard and are
+// matched against both the full target URL and its hostname.
+type Dependency struct {
+	Targets   []string
+	DependsOn []string
 }
 
 // WebhookConfig for Discord/Slack/custom webhooks
@@ -30,125 +126,528 @@ type Alert struct {
 	Timestamp time.Time
 }
 
-// Alerter manages sending alerts with cooldown
+// incident tracks an open outage and how far it has escalated
+type incident struct {
+	route    *Route
+	started  time.Time
+	step     int // Index of the last step reached, -1 before the first
+	lastSent time.Time
+	notified map[string]bool
+}
+
+// Alerter manages sending alerts with escalation and cooldown
 type Alerter struct {
-	config    Config
-	lastAlert map[string]time.Time
+	config     Config
+	notifiers  map[string]Notifier
+	routes     []Route
+	incidents  map[string]*incident
+	down       map[string]bool
+	suppressed map[string]string  // child URL -> parent incident URL
+	pending    map[string][]Event // notifier name -> events awaiting a digest
+	certLevels map[string]string  // certificate serial -> severity already sent
+
+	latency          map[string][]latencySample
+	latencyAlerted   map[string]time.Time // rule index|URL -> last latency alert
+	latencyRetention time.Duration
+
 	mu        sync.Mutex
+	historyMu sync.Mutex
 	client    *http.Client
 }
 
-// New creates a new Alerter
+// New creates a new Alerter. Notifiers that fail validation are skipped;
+// call Config.Validate first to report them.
 func New(config Config) *Alerter {
-	return &Alerter{
-		config:    config,
-		lastAlert: make(map[string]time.Time),
-		client:    &http.Client{Timeout: 10 * time.Second},
+	a := &Alerter{
+		config:     config,
+		notifiers:  make(map[string]Notifier),
+		incidents:  make(map[string]*incident),
+		down:       make(map[string]bool),
+		suppressed: make(map[string]string),
+		pending:    make(map[string][]Event),
+		certLevels: make(map[string]string),
+		client:     &http.Client{Timeout: 10 * time.Second},
+
+		latency:          make(map[string][]latencySample),
+		latencyAlerted:   make(map[string]time.Time),
+		latencyRetention: latencyRetention(config.LatencyRules),
+	}
+
+	if config.Webhook != nil {
+		a.notifiers[defaultNotifier] = &webhookNotifier{config: *config.Webhook, client: a.client}
+	}
+	for name, nc := range config.Notifiers {
+		if n, err := newNotifier(nc, a.client); err == nil {
+			a.notifiers[name] = n
+		}
+	}
+
+	a.routes = config.Routes
+	if len(a.routes) == 0 && config.Webhook != nil {
+		// Without routes every target alerts the webhook at once
+		a.routes = []Route{{
+			Steps: []EscalationStep{{Notifiers: []string{defaultNotifier}}},
+		}}
+	}
+
+	return a
+}
+
+// UpdateState records the latest health of a target. It should be called for
+// every result of a cycle before alerts are sent, so that dependency
+// suppression sees parents and children from the same cycle.
+func (a *Alerter) UpdateState(url string, healthy bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if healthy {
+		delete(a.down, url)
+	} else {
+		a.down[url] = true
 	}
 }
 
-// SendAlert sends an alert if cooldown has passed
+// SendAlert opens or advances the target's incident and notifies every
+// escalation step that has become due
 func (a *Alerter) SendAlert(alert Alert) error {
 	if !a.config.Enabled {
 		return nil
 	}
 
 	a.mu.Lock()
-	lastTime, exists := a.lastAlert[alert.URL]
-	if exists && time.Since(lastTime) < a.config.Cooldown {
+	if parent := a.rootCause(alert.URL); parent != "" {
+		// Grouped under the parent's incident
+		a.suppressed[alert.URL] = parent
 		a.mu.Unlock()
-		return nil // Still in cooldown
+		a.recordEvent(Event{Kind: EventFailure, Alert: alert}, DecisionSuppressed, "grouped under "+parent, "", nil)
+		return nil
 	}
-	a.lastAlert[alert.URL] = time.Now()
+	delete(a.suppressed, alert.URL)
+
+	inc, ok := a.incidents[alert.URL]
+	if !ok {
+		route := a.routeFor(alert.URL)
+		if route == nil {
+			a.mu.Unlock()
+			a.recordEvent(Event{Kind: EventFailure, Alert: alert}, DecisionSuppressed, "no matching route", "", nil)
+			return nil
+		}
+		started := alert.Timestamp
+		if started.IsZero() {
+			started = time.Now()
+		}
+		inc = &incident{route: route, started: started, step: -1, notified: make(map[string]bool)}
+		a.incidents[alert.URL] = inc
+	}
+
+	event := Event{Kind: EventFailure, Alert: alert, Dependents: a.dependentsOf(alert.URL)}
+	targets, reason := a.escalate(inc, &event)
 	a.mu.Unlock()
 
-	if a.config.Webhook != nil {
-		return a.sendWebhook(alert)
+	if len(targets) == 0 {
+		a.recordEvent(event, DecisionSuppressed, reason, "", nil)
+		return nil
+	}
+	return a.dispatch(targets, event)
+}
+
+// SendCertAlert sends a "certificate expiring" alert when the certificate
+// has crossed the warning or critical threshold. Each serial alerts once per
+// severity, so a renewed certificate starts over.
+func (a *Alerter) SendCertAlert(url string, cert CertInfo) error {
+	th := a.config.CertExpiry
+	if !a.config.Enabled || th == nil || cert.Serial == "" {
+		return nil
+	}
+
+	severity := ""
+	switch {
+	case th.CriticalDays > 0 && cert.DaysLeft <= th.CriticalDays:
+		severity = SeverityCritical
+	case th.WarningDays > 0 && cert.DaysLeft <= th.WarningDays:
+		severity = SeverityWarning
+	default:
+		return nil
+	}
+
+	event := Event{
+		Kind:     EventCertExpiring,
+		Alert:    Alert{URL: url, Status: "Certificate expiring", Timestamp: time.Now()},
+		Cert:     &cert,
+		Severity: severity,
+	}
+
+	a.mu.Lock()
+	sent := a.certLevels[cert.Serial]
+	if sent == severity || sent == SeverityCritical {
+		a.mu.Unlock()
+		a.recordEvent(event, DecisionSuppressed, "already sent", "", nil)
+		return nil
 	}
+	route := a.routeFor(url)
+	if route == nil {
+		a.mu.Unlock()
+		a.recordEvent(event, DecisionSuppressed, "no matching route", "", nil)
+		return nil
+	}
+	// Claimed before sending so that a concurrent check doesn't send it
+	// too, and released if delivery fails so that the next check retries
+	a.certLevels[cert.Serial] = severity
+	a.mu.Unlock()
 
+	// Expiry warnings are not outages, so they only go to the first step
+	if err := a.dispatch(route.Steps[0].Notifiers, event); err != nil {
+		a.mu.Lock()
+		if a.certLevels[cert.Serial] == severity {
+			if sent == "" {
+				delete(a.certLevels, cert.Serial)
+			} else {
+				a.certLevels[cert.Serial] = sent
+			}
+		}
+		a.mu.Unlock()
+		return err
+	}
 	return nil
 }
 
-func (a *Alerter) sendWebhook(alert Alert) error {
-	// Discord webhook format
-	payload := map[string]interface{}{
-		"embeds": []map[string]interface{}{
-			{
-				"title":       "🚨 GoPunch Alert",
-				"description": fmt.Sprintf("**URL:** %s\n**Status:** %s", alert.URL, alert.Status),
-				"color":       16711680, // Red
-				"timestamp":   alert.Timestamp.Format(time.RFC3339),
-				"footer": map[string]string{
-					"text": "GoPunch Monitoring",
-				},
-			},
-		},
+// escalate advances the incident to every step that is due and returns the
+// notifiers to alert. When no new step is due, a reminder for the current
+// step is returned once the repeat interval has passed. Otherwise it returns
+// the reason nothing is sent. Callers must hold a.mu.
+func (a *Alerter) escalate(inc *incident, event *Event) ([]string, string) {
+	now := time.Now()
+	elapsed := now.Sub(inc.started)
+
+	var targets []string
+	for inc.step+1 < len(inc.route.Steps) && inc.route.Steps[inc.step+1].After <= elapsed {
+		inc.step++
+		targets = append(targets, inc.route.Steps[inc.step].Notifiers...)
 	}
+	event.Step = inc.step
 
-	if alert.Error != "" {
-		payload["embeds"].([]map[string]interface{})[0]["description"] =
-			fmt.Sprintf("**URL:** %s\n**Error:** %s", alert.URL, alert.Error)
+	if len(targets) == 0 {
+		if inc.step < 0 {
+			return nil, "waiting for first escalation step"
+		}
+		repeat := inc.route.RepeatInterval
+		if repeat == 0 {
+			repeat = a.config.Cooldown
+		}
+		if now.Sub(inc.lastSent) < repeat {
+			return nil, "cooldown"
+		}
+		targets = inc.route.Steps[inc.step].Notifiers
+		event.Reminder = true
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	inc.lastSent = now
+	for _, n := range targets {
+		inc.notified[n] = true
 	}
+	return targets, ""
+}
 
-	method := a.config.Webhook.Method
-	if method == "" {
-		method = "POST"
+// routeFor returns the first route matching url. Callers must hold a.mu.
+func (a *Alerter) routeFor(url string) *Route {
+	for i := range a.routes {
+		if matchAny(a.routes[i].Targets, url) {
+			return &a.routes[i]
+		}
 	}
+	return nil
+}
 
-	req, err := http.NewRequest(method, a.config.Webhook.URL, bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("failed to create webhook request: %w", err)
+// matchAny reports whether any pattern matches the target. An empty list
+// matches every target.
+func matchAny(patterns []string, target string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matchTarget(pattern, target) {
+			return true
+		}
 	}
+	return false
+}
 
-	req.Header.Set("Content-Type", "application/json")
+// dispatch sends the event to each named notifier once, or queues it for
+// the next digest when a group window is configured
+func (a *Alerter) dispatch(names []string, event Event) error {
+	seen := make(map[string]bool)
+	var errs []error
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		n, ok := a.notifiers[name]
+		if !ok {
+			a.recordEvent(event, DecisionFailed, "unknown notifier", name, nil)
+			continue
+		}
+		if a.config.GroupWindow > 0 {
+			a.enqueue(name, event)
+			a.recordEvent(event, DecisionQueued, "digest window", name, nil)
+			continue
+		}
+		if err := a.deliver(name, n, []Event{event}, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send webhook: %w", err)
+// eventRunner is implemented by notifiers that handle each event on its own
+// and capture output worth keeping in the history, such as command hooks
+type eventRunner interface {
+	run(event Event) (string, error)
+}
+
+// deliver sends events to a notifier and records the outcome of each
+func (a *Alerter) deliver(name string, n Notifier, events []Event, reason string) error {
+	if r, ok := n.(eventRunner); ok {
+		var errs []error
+		for _, e := range events {
+			output, err := r.run(e)
+			a.recordDelivery(e, reason, name, output, err)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	err := n.Notify(events)
+	for _, e := range events {
+		a.recordDelivery(e, reason, name, "", err)
 	}
+	return err
+}
 
+// enqueue adds an event to the notifier's pending digest. The first event
+// of a batch starts the window; everything raised before it closes is sent
+// together.
+func (a *Alerter) enqueue(name string, event Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.pending[name]) == 0 {
+		time.AfterFunc(a.config.GroupWindow, func() { a.flush(name) })
+	}
+	a.pending[name] = append(a.pending[name], event)
+}
+
+// flush sends the notifier's pending digest, if any
+func (a *Alerter) flush(name string) error {
+	a.mu.Lock()
+	events := a.pending[name]
+	delete(a.pending, name)
+	a.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+	err := a.deliver(name, a.notifiers[name], events, fmt.Sprintf("digest of %d", len(events)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
 	return nil
 }
 
-// SendRecoveryAlert sends a recovery notification
+// Flush immediately sends every pending digest. It should be called before
+// exiting so that batched alerts are not lost.
+func (a *Alerter) Flush() error {
+	a.mu.Lock()
+	var names []string
+	for name := range a.pending {
+		names = append(names, name)
+	}
+	a.mu.Unlock()
+
+	var errs []error
+	for _, name := range names {
+		if err := a.flush(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rootCause returns the topmost down target that url depends on, or "" if
+// all of its dependencies are healthy. Callers must hold a.mu.
+func (a *Alerter) rootCause(url string) string {
+	root := ""
+	visited := map[string]bool{url: true}
+	current := url
+	for {
+		parent := ""
+		for _, p := range a.parentsOf(current) {
+			if a.down[p] && !visited[p] {
+				parent = p
+				break
+			}
+		}
+		if parent == "" {
+			return root
+		}
+		visited[parent] = true
+		root = parent
+		current = parent
+	}
+}
+
+// parentsOf returns the targets that url directly depends on
+func (a *Alerter) parentsOf(url string) []string {
+	var parents []string
+	for _, dep := range a.config.Dependencies {
+		for _, pattern := range dep.Targets {
+			if matchTarget(pattern, url) {
+				for _, p := range dep.DependsOn {
+					if p != url {
+						parents = append(parents, p)
+					}
+				}
+				break
+			}
+		}
+	}
+	return parents
+}
+
+// dependentsOf lists the down targets whose failures are grouped under the
+// parent's incident. Callers must hold a.mu.
+func (a *Alerter) dependentsOf(parent string) []string {
+	var children []string
+	for url := range a.down {
+		if url != parent && a.rootCause(url) == parent {
+			children = append(children, url)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// matchTarget reports whether a dependency pattern matches a target, either
+// by its full URL or by its hostname.
+func matchTarget(pattern, target string) bool {
+	if matchWildcard(pattern, target) {
+		return true
+	}
+	if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+		return matchWildcard(pattern, u.Hostname())
+	}
+	return false
+}
+
+// matchWildcard matches s against a pattern where "*" matches any sequence
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(s, part)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// SendRecoveryAlert closes the target's incident and notifies everyone who
+// was alerted about it
 func (a *Alerter) SendRecoveryAlert(url string) error {
-	if !a.config.Enabled || a.config.Webhook == nil {
+	if !a.config.Enabled || len(a.notifiers) == 0 {
 		return nil
 	}
 
-	payload := map[string]interface{}{
-		"embeds": []map[string]interface{}{
-			{
-				"title":       "✅ GoPunch Recovery",
-				"description": fmt.Sprintf("**URL:** %s\n**Status:** Back online", url),
-				"color":       65280, // Green
-				"timestamp":   time.Now().Format(time.RFC3339),
-				"footer": map[string]string{
-					"text": "GoPunch Monitoring",
-				},
-			},
-		},
+	a.mu.Lock()
+	if parent, ok := a.suppressed[url]; ok {
+		delete(a.suppressed, url)
+		// Only skip the recovery when the failure was never announced on
+		// its own, e.g. before the parent went down. The incident closes
+		// either way so the next failure escalates from the start.
+		if inc, has := a.incidents[url]; !has || len(inc.notified) == 0 {
+			delete(a.incidents, url)
+			a.mu.Unlock()
+			a.recordEvent(Event{Kind: EventRecovery, Alert: Alert{URL: url}}, DecisionSuppressed, "failure was grouped under "+parent, "", nil)
+			return nil
+		}
+	}
+	inc, ok := a.incidents[url]
+	delete(a.incidents, url)
+	var grouped []string
+	for child, parent := range a.suppressed {
+		if parent == url {
+			grouped = append(grouped, child)
+		}
 	}
+	a.mu.Unlock()
 
-	body, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", a.config.Webhook.URL, bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
+	if !ok || len(inc.notified) == 0 {
+		a.recordEvent(Event{Kind: EventRecovery, Alert: Alert{URL: url}}, DecisionSuppressed, "no failure was sent", "", nil)
+		return nil
+	}
+	sort.Strings(grouped)
 
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return err
+	var targets []string
+	for name := range inc.notified {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+
+	return a.dispatch(targets, Event{
+		Kind:       EventRecovery,
+		Alert:      Alert{URL: url, Timestamp: time.Now()},
+		Dependents: grouped,
+		Step:       inc.step,
+	})
+}
+
+// Notifiers returns the names of the configured notifiers
+func (a *Alerter) Notifiers() []string {
+	var names []string
+	for name := range a.notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SendTest sends a sample failure followed by a recovery straight to the
+// named notifier, bypassing routes, cooldowns and digests
+func (a *Alerter) SendTest(name string) error {
+	n, ok := a.notifiers[name]
+	if !ok {
+		return fmt.Errorf("unknown notifier %q", name)
+	}
+
+	const testURL = "https://gopunch.test/health"
+	events := []Event{
+		{
+			Kind: EventFailure,
+			Alert: Alert{
+				URL:       testURL,
+				Status:    "503 Service Unavailable",
+				Error:     "This is a test alert from GoPunch",
+				Timestamp: time.Now(),
+			},
+		},
+		{
+			Kind:  EventRecovery,
+			Alert: Alert{URL: testURL, Timestamp: time.Now()},
+		},
 	}
-	defer resp.Body.Close()
 
+	for _, e := range events {
+		if err := a.deliver(name, n, []Event{e}, "test"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,176 @@
+package alerter
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder is a Notifier that keeps what it was sent, or fails with err
+type recorder struct {
+	events []Event
+	err    error
+}
+
+func (r *recorder) Notify(events []Event) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *recorder) kinds(url string) []EventKind {
+	var kinds []EventKind
+	for _, e := range r.events {
+		if e.Alert.URL == url {
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	return kinds
+}
+
+func TestEscalate(t *testing.T) {
+	route := &Route{
+		Steps: []EscalationStep{
+			{After: 0, Notifiers: []string{"chat"}},
+			{After: 10 * time.Minute, Notifiers: []string{"mail"}},
+			{After: 30 * time.Minute, Notifiers: []string{"pager"}},
+		},
+		RepeatInterval: 5 * time.Minute,
+	}
+	delayed := &Route{Steps: []EscalationStep{{After: 5 * time.Minute, Notifiers: []string{"chat"}}}}
+	noRepeat := &Route{Steps: []EscalationStep{{Notifiers: []string{"chat"}}}}
+
+	tests := []struct {
+		name     string
+		route    *Route
+		started  time.Duration // Ago
+		step     int
+		lastSent time.Duration // Ago, or zero if never
+		want     []string
+		reason   string
+		wantStep int
+		reminder bool
+	}{
+		{name: "new incident", route: route, step: -1, want: []string{"chat"}, wantStep: 0},
+		{name: "first step not due", route: delayed, step: -1, reason: "waiting for first escalation step", wantStep: -1},
+		{name: "cooldown", route: route, started: 2 * time.Minute, step: 0, lastSent: 2 * time.Minute, reason: "cooldown", wantStep: 0},
+		{name: "reminder", route: route, started: 6 * time.Minute, step: 0, lastSent: 6 * time.Minute, want: []string{"chat"}, wantStep: 0, reminder: true},
+		{name: "next step", route: route, started: 11 * time.Minute, step: 0, lastSent: time.Minute, want: []string{"mail"}, wantStep: 1},
+		{name: "skipped steps", route: route, started: 31 * time.Minute, step: -1, want: []string{"chat", "mail", "pager"}, wantStep: 2},
+		{name: "cooldown without repeat interval", route: noRepeat, started: 2 * time.Minute, step: 0, lastSent: 2 * time.Minute, reason: "cooldown", wantStep: 0},
+		{name: "reminder without repeat interval", route: noRepeat, started: 4 * time.Minute, step: 0, lastSent: 4 * time.Minute, want: []string{"chat"}, wantStep: 0, reminder: true},
+	}
+
+	a := New(Config{Enabled: true, Cooldown: 3 * time.Minute})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			inc := &incident{route: tt.route, started: now.Add(-tt.started), step: tt.step, notified: map[string]bool{}}
+			if tt.lastSent > 0 {
+				inc.lastSent = now.Add(-tt.lastSent)
+			}
+
+			var event Event
+			got, reason := a.escalate(inc, &event)
+			if !reflect.DeepEqual(got, tt.want) || reason != tt.reason {
+				t.Errorf("escalate() = %v, %q, want %v, %q", got, reason, tt.want, tt.reason)
+			}
+			if event.Step != tt.wantStep || inc.step != tt.wantStep {
+				t.Errorf("step = %d (event %d), want %d", inc.step, event.Step, tt.wantStep)
+			}
+			if event.Reminder != tt.reminder {
+				t.Errorf("reminder = %v, want %v", event.Reminder, tt.reminder)
+			}
+			for _, n := range tt.want {
+				if !inc.notified[n] {
+					t.Errorf("%s not marked as notified", n)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	notifiers := map[string]NotifierConfig{
+		"chat": {Webhook: &WebhookConfig{URL: "https://chat.example.com/hook"}},
+		"mail": {Webhook: &WebhookConfig{URL: "https://mail.example.com/hook"}},
+	}
+	tests := []struct {
+		name    string
+		steps   []EscalationStep
+		wantErr bool
+	}{
+		{name: "ordered", steps: []EscalationStep{{Notifiers: []string{"chat"}}, {After: 10 * time.Minute, Notifiers: []string{"mail"}}}},
+		{name: "same time", steps: []EscalationStep{{Notifiers: []string{"chat"}}, {Notifiers: []string{"mail"}}}},
+		{name: "unordered", steps: []EscalationStep{{After: 10 * time.Minute, Notifiers: []string{"mail"}}, {Notifiers: []string{"chat"}}}, wantErr: true},
+		{name: "unknown notifier", steps: []EscalationStep{{Notifiers: []string{"pager"}}}, wantErr: true},
+		{name: "no steps", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Notifiers: notifiers, Routes: []Route{{Steps: tt.steps}}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendCertAlert(t *testing.T) {
+	const url = "ssl://example.com:443"
+	var history bytes.Buffer
+	rec := &recorder{}
+	a := New(Config{
+		Enabled:    true,
+		CertExpiry: &CertExpiryConfig{WarningDays: 30, CriticalDays: 7},
+		Routes:     []Route{{Steps: []EscalationStep{{Notifiers: []string{"rec"}}}}},
+		History:    &history,
+	})
+	a.notifiers["rec"] = rec
+
+	// Each check reports the certificate in use; want is the severity sent
+	tests := []struct {
+		serial string
+		days   int
+		fail   bool
+		want   string
+	}{
+		{serial: "01", days: 60},
+		{serial: "01", days: 30, want: SeverityWarning},
+		{serial: "01", days: 29},
+		{serial: "01", days: 7, fail: true},
+		{serial: "01", days: 6, want: SeverityCritical},
+		{serial: "01", days: 5},
+		{serial: "01", days: 20},
+		{serial: "02", days: 25, want: SeverityWarning},
+		{serial: "02", days: 3, want: SeverityCritical},
+	}
+	for i, tt := range tests {
+		rec.events = nil
+		rec.err = nil
+		if tt.fail {
+			rec.err = errors.New("webhook down")
+		}
+		err := a.SendCertAlert(url, CertInfo{Serial: tt.serial, DaysLeft: tt.days})
+		if (err != nil) != tt.fail {
+			t.Fatalf("check %d: SendCertAlert() = %v", i+1, err)
+		}
+
+		got := ""
+		if len(rec.events) > 0 {
+			got = rec.events[0].Severity
+		}
+		if len(rec.events) > 1 || got != tt.want {
+			t.Errorf("check %d (serial %s, %d days): sent %d alerts (%q), want %q", i+1, tt.serial, tt.days, len(rec.events), got, tt.want)
+		}
+	}
+
+	if !strings.Contains(history.String(), `"reason":"already sent"`) {
+		t.Errorf("history has no entry for skipped alerts:\n%s", history.String())
+	}
+}
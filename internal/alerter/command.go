@@ -0,0 +1,123 @@
+package alerter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCommandOutput caps how much hook output is kept for error messages
+// and the alert history
+const maxCommandOutput = 4096
+
+// commandWaitDelay is how long to wait for the output pipes to close after
+// the command exits or is killed
+const commandWaitDelay = 2 * time.Second
+
+// CommandConfig runs a local command for every event. Event details are
+// passed as GOPUNCH_* environment variables and as JSON on stdin.
+type CommandConfig struct {
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+type commandNotifier struct {
+	config CommandConfig
+}
+
+// commandPayload is the JSON document written to the command's stdin
+type commandPayload struct {
+	Kind       EventKind `json:"kind"`
+	URL        string    `json:"url"`
+	Status     string    `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Step       int       `json:"step"`
+	Reminder   bool      `json:"reminder"`
+	Severity   string    `json:"severity,omitempty"`
+	Dependents []string  `json:"dependents,omitempty"`
+}
+
+// Notify runs the command once per event so that each target can be
+// handled on its own, even within a digest
+func (c *commandNotifier) Notify(events []Event) error {
+	var errs []error
+	for _, e := range events {
+		if _, err := c.run(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run executes the command for one event and returns its combined output,
+// truncated to maxCommandOutput
+func (c *commandNotifier) run(event Event) (string, error) {
+	timeout := c.config.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(commandPayload{
+		Kind:       event.Kind,
+		URL:        event.Alert.URL,
+		Status:     event.Alert.Status,
+		Error:      event.Alert.Error,
+		Timestamp:  event.timestamp(),
+		Step:       event.Step,
+		Reminder:   event.Reminder,
+		Severity:   event.Severity,
+		Dependents: event.Dependents,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal command payload: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, c.config.Command, c.config.Args...)
+	startProcessGroup(cmd)
+	// Background children can hold the output pipes open after the
+	// command itself has exited or been killed
+	cmd.WaitDelay = commandWaitDelay
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"GOPUNCH_EVENT="+string(event.Kind),
+		"GOPUNCH_URL="+event.Alert.URL,
+		"GOPUNCH_STATUS="+event.Alert.Status,
+		"GOPUNCH_ERROR="+event.Alert.Error,
+		"GOPUNCH_TIMESTAMP="+event.timestamp().Format(time.RFC3339),
+		"GOPUNCH_STEP="+strconv.Itoa(event.Step),
+		"GOPUNCH_DEPENDENTS="+strings.Join(event.Dependents, ","),
+	)
+
+	output, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(output))
+	if len(out) > maxCommandOutput {
+		out = out[:maxCommandOutput] + "..."
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return out, fmt.Errorf("command timed out after %s", timeout)
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// The command succeeded but left a child holding its output open,
+		// e.g. a service it restarted
+		err = nil
+	}
+	if err != nil {
+		if out != "" {
+			return out, fmt.Errorf("command failed: %w: %s", err, out)
+		}
+		return out, fmt.Errorf("command failed: %w", err)
+	}
+	return out, nil
+}
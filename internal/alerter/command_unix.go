@@ -0,0 +1,17 @@
+//go:build !windows
+
+package alerter
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startProcessGroup runs the command in its own process group so that a
+// timeout also stops anything it started in the background
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package alerter
+
+import "os/exec"
+
+// startProcessGroup is a no-op on Windows, where cancelling the command
+// kills only the process itself; WaitDelay still bounds the wait for
+// output from children it leaves behind
+func startProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,166 @@
+package alerter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDependentRecovery(t *testing.T) {
+	const (
+		parent = "tcp://gateway:443"
+		child  = "https://api.example.com"
+	)
+
+	// Each step reports one target's health for a cycle, as watch does
+	type step struct {
+		url     string
+		healthy bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  []EventKind // What the child's notifier received
+	}{
+		{
+			name:  "failure then recovery",
+			steps: []step{{child, false}, {child, true}},
+			want:  []EventKind{EventFailure, EventRecovery},
+		},
+		{
+			name:  "recovery without failure",
+			steps: []step{{child, true}},
+		},
+		{
+			name:  "grouped under parent",
+			steps: []step{{parent, false}, {child, false}, {child, true}},
+		},
+		{
+			name:  "alerted before parent went down",
+			steps: []step{{child, false}, {parent, false}, {child, false}, {child, true}},
+			want:  []EventKind{EventFailure, EventRecovery},
+		},
+		{
+			name: "fails again after grouped recovery",
+			steps: []step{
+				{parent, false}, {child, false}, {child, true},
+				{parent, true}, {child, false},
+			},
+			want: []EventKind{EventFailure},
+		},
+		{
+			name: "fails again after recovery",
+			steps: []step{
+				{child, false}, {parent, false}, {child, false}, {child, true},
+				{parent, true}, {child, false},
+			},
+			want: []EventKind{EventFailure, EventRecovery, EventFailure},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			a := New(Config{
+				Enabled:  true,
+				Cooldown: time.Hour,
+				Dependencies: []Dependency{
+					{Targets: []string{"api.*"}, DependsOn: []string{parent}},
+				},
+				Routes: []Route{{Steps: []EscalationStep{{Notifiers: []string{"rec"}}}}},
+			})
+			a.notifiers["rec"] = rec
+
+			down := map[string]bool{}
+			for _, s := range tt.steps {
+				a.UpdateState(s.url, s.healthy)
+				var err error
+				switch {
+				case !s.healthy:
+					down[s.url] = true
+					err = a.SendAlert(Alert{URL: s.url, Error: "down"})
+				case down[s.url]:
+					delete(down, s.url)
+					err = a.SendRecoveryAlert(s.url)
+				default:
+					err = a.SendRecoveryAlert(s.url)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := rec.kinds(child); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("child got %v, want %v", got, tt.want)
+			}
+			if !down[child] {
+				if _, open := a.incidents[child]; open {
+					t.Error("incident left open after recovery")
+				}
+				if _, grouped := a.suppressed[child]; grouped {
+					t.Error("child still grouped after recovery")
+				}
+			}
+		})
+	}
+}
+
+func TestParentRecoveryListsGroupedChildren(t *testing.T) {
+	const (
+		parent = "tcp://gateway:443"
+		child  = "https://api.example.com"
+	)
+	rec := &recorder{}
+	a := New(Config{
+		Enabled:      true,
+		Dependencies: []Dependency{{Targets: []string{"api.*"}, DependsOn: []string{parent}}},
+		Routes:       []Route{{Steps: []EscalationStep{{Notifiers: []string{"rec"}}}}},
+	})
+	a.notifiers["rec"] = rec
+
+	a.UpdateState(parent, false)
+	a.UpdateState(child, false)
+	if err := a.SendAlert(Alert{URL: parent}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SendAlert(Alert{URL: child}); err != nil {
+		t.Fatal(err)
+	}
+	a.UpdateState(parent, true)
+	if err := a.SendRecoveryAlert(parent); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.events) != 2 {
+		t.Fatalf("got %d events, want 2", len(rec.events))
+	}
+	if got := rec.events[0].Dependents; !reflect.DeepEqual(got, []string{child}) {
+		t.Errorf("failure dependents = %v, want [%s]", got, child)
+	}
+	if got := rec.events[1].Dependents; !reflect.DeepEqual(got, []string{child}) {
+		t.Errorf("recovery dependents = %v, want [%s]", got, child)
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"api.example.com", "api.example.org", false},
+		{"*", "anything", true},
+		{"api.*", "api.example.com", true},
+		{"api.*", "www.example.com", false},
+		{"*.example.com", "api.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"https://*/health", "https://api.example.com/health", true},
+		{"a*b*c", "abc", true},
+		{"a*b*c", "acb", false},
+	}
+	for _, tt := range tests {
+		if got := matchWildcard(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchWildcard(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
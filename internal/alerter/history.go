@@ -0,0 +1,81 @@
+package alerter
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Alert decisions recorded in the history log
+const (
+	DecisionSent       = "sent"
+	DecisionSuppressed = "suppressed"
+	DecisionQueued     = "queued"
+	DecisionFailed     = "failed"
+)
+
+// HistoryEntry is one line of the alert history log
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	URL      string    `json:"url"`
+	Kind     EventKind `json:"kind"`
+	Decision string    `json:"decision"`
+	Reason   string    `json:"reason,omitempty"`
+	Notifier string    `json:"notifier,omitempty"`
+	Step     int       `json:"step"`
+	Error    string    `json:"error,omitempty"`
+	Output   string    `json:"output,omitempty"` // Captured output of command hooks
+}
+
+// record appends an entry to the history log, if one is configured
+func (a *Alerter) record(entry HistoryEntry) {
+	if a.config.History == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	a.config.History.Write(append(line, '\n'))
+}
+
+// recordEvent logs a decision about an event
+func (a *Alerter) recordEvent(event Event, decision, reason, notifier string, err error) {
+	entry := HistoryEntry{
+		URL:      event.Alert.URL,
+		Kind:     event.Kind,
+		Decision: decision,
+		Reason:   reason,
+		Notifier: notifier,
+		Step:     event.Step,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	a.record(entry)
+}
+
+// recordDelivery logs the outcome of handing an event to a notifier,
+// with any output the notifier captured
+func (a *Alerter) recordDelivery(event Event, reason, notifier, output string, err error) {
+	entry := HistoryEntry{
+		URL:      event.Alert.URL,
+		Kind:     event.Kind,
+		Decision: DecisionSent,
+		Reason:   reason,
+		Notifier: notifier,
+		Step:     event.Step,
+		Output:   output,
+	}
+	if err != nil {
+		entry.Decision = DecisionFailed
+		entry.Error = err.Error()
+	}
+	a.record(entry)
+}
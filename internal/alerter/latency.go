@@ -0,0 +1,202 @@
+package alerter
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// minBaselineSamples is how many samples the baseline window needs before
+// deviation rules are evaluated
+const minBaselineSamples = 10
+
+// LatencyRule raises an alert when a target is up but slow. The observed
+// latency is the Percentile of samples within Window (the latest sample
+// when Window is zero). It breaches when it exceeds Threshold, or when it is
+// more than Factor times the median over Baseline. Alerts for a target repeat
+// at most once per Cooldown (Config.Cooldown when zero).
+type LatencyRule struct {
+	Targets    []string
+	Threshold  time.Duration
+	Percentile float64
+	Window     time.Duration
+	Baseline   time.Duration
+	Factor     float64
+	Cooldown   time.Duration
+}
+
+// LatencyInfo describes a latency rule breach
+type LatencyInfo struct {
+	Observed   time.Duration
+	Percentile float64       // Zero when only the latest sample was used
+	Threshold  time.Duration // Fixed limit that was exceeded, if any
+	Baseline   time.Duration // Baseline median, for deviation breaches
+	Factor     float64
+}
+
+// Reason explains the breach in one line
+func (l LatencyInfo) Reason() string {
+	observed := fmt.Sprintf("latest %dms", l.Observed.Milliseconds())
+	if l.Percentile > 0 {
+		observed = fmt.Sprintf("p%g %dms", l.Percentile, l.Observed.Milliseconds())
+	}
+	if l.Threshold > 0 {
+		return fmt.Sprintf("%s exceeds %dms", observed, l.Threshold.Milliseconds())
+	}
+	return fmt.Sprintf("%s is over %gx the baseline median of %dms", observed, l.Factor, l.Baseline.Milliseconds())
+}
+
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// RecordLatency stores a response time for a healthy check and alerts when
+// one of the latency rules matching url is breached
+func (a *Alerter) RecordLatency(url string, d time.Duration) error {
+	if !a.config.Enabled || len(a.config.LatencyRules) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	a.mu.Lock()
+	samples := append(a.latency[url], latencySample{at: now, duration: d})
+	samples = pruneSamples(samples, now.Add(-a.latencyRetention))
+	a.latency[url] = samples
+
+	var breach *LatencyInfo
+	cooling := false
+	for i, rule := range a.config.LatencyRules {
+		if !matchAny(rule.Targets, url) {
+			continue
+		}
+		info := evaluateLatency(rule, samples, now)
+		if info == nil {
+			continue
+		}
+		cooldown := rule.Cooldown
+		if cooldown == 0 {
+			cooldown = a.config.Cooldown
+		}
+		key := fmt.Sprintf("%d|%s", i, url)
+		if last, ok := a.latencyAlerted[key]; ok && now.Sub(last) < cooldown {
+			cooling = true
+			continue
+		}
+		a.latencyAlerted[key] = now
+		breach = info
+		break
+	}
+	var route *Route
+	if breach != nil {
+		route = a.routeFor(url)
+	}
+	a.mu.Unlock()
+
+	if breach == nil {
+		if cooling {
+			a.recordEvent(Event{Kind: EventLatency, Alert: Alert{URL: url}}, DecisionSuppressed, "cooldown", "", nil)
+		}
+		return nil
+	}
+
+	event := Event{
+		Kind:     EventLatency,
+		Alert:    Alert{URL: url, Status: breach.Reason(), Timestamp: now},
+		Latency:  breach,
+		Severity: SeverityWarning,
+	}
+	if route == nil {
+		a.recordEvent(event, DecisionSuppressed, "no matching route", "", nil)
+		return nil
+	}
+
+	return a.dispatch(route.Steps[0].Notifiers, event)
+}
+
+// evaluateLatency returns the breach described by rule, or nil
+func evaluateLatency(rule LatencyRule, samples []latencySample, now time.Time) *LatencyInfo {
+	pct := rule.Percentile
+	if pct <= 0 {
+		pct = 95
+	}
+
+	var observed time.Duration
+	if rule.Window > 0 {
+		window := durationsSince(samples, now.Add(-rule.Window))
+		if len(window) == 0 {
+			return nil
+		}
+		observed = percentile(window, pct)
+	} else if len(samples) > 0 {
+		observed = samples[len(samples)-1].duration
+		pct = 0
+	} else {
+		return nil
+	}
+
+	if rule.Threshold > 0 && observed > rule.Threshold {
+		return &LatencyInfo{Observed: observed, Percentile: pct, Threshold: rule.Threshold}
+	}
+
+	if rule.Factor > 0 && rule.Baseline > 0 {
+		baseline := durationsSince(samples, now.Add(-rule.Baseline))
+		if len(baseline) < minBaselineSamples {
+			return nil
+		}
+		median := percentile(baseline, 50)
+		if median > 0 && float64(observed) > rule.Factor*float64(median) {
+			return &LatencyInfo{Observed: observed, Percentile: pct, Baseline: median, Factor: rule.Factor}
+		}
+	}
+
+	return nil
+}
+
+// latencyRetention is the longest window any rule looks back over
+func latencyRetention(rules []LatencyRule) time.Duration {
+	var max time.Duration
+	for _, r := range rules {
+		if r.Window > max {
+			max = r.Window
+		}
+		if r.Baseline > max {
+			max = r.Baseline
+		}
+	}
+	return max
+}
+
+// pruneSamples drops samples older than cutoff, always keeping the latest
+func pruneSamples(samples []latencySample, cutoff time.Time) []latencySample {
+	i := 0
+	for i < len(samples)-1 && samples[i].at.Before(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
+
+func durationsSince(samples []latencySample, cutoff time.Time) []time.Duration {
+	var out []time.Duration
+	for _, s := range samples {
+		if !s.at.Before(cutoff) {
+			out = append(out, s.duration)
+		}
+	}
+	return out
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of values
+func percentile(values []time.Duration, p float64) time.Duration {
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
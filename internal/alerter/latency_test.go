@@ -0,0 +1,170 @@
+package alerter
+
+import (
+	"testing"
+	"time"
+)
+
+// samplesOf returns one sample per minute, the last one at now
+func samplesOf(now time.Time, ms ...int) []latencySample {
+	samples := make([]latencySample, len(ms))
+	for i, m := range ms {
+		samples[i] = latencySample{
+			at:       now.Add(-time.Duration(len(ms)-1-i) * time.Minute),
+			duration: time.Duration(m) * time.Millisecond,
+		}
+	}
+	return samples
+}
+
+func TestEvaluateLatency(t *testing.T) {
+	now := time.Now()
+	steady := []int{100, 110, 90, 100, 105, 95, 100, 100, 110, 90}
+
+	tests := []struct {
+		name    string
+		rule    LatencyRule
+		samples []latencySample
+		want    *LatencyInfo
+	}{
+		{
+			name: "no samples",
+			rule: LatencyRule{Threshold: time.Second},
+		},
+		{
+			name:    "latest under threshold",
+			rule:    LatencyRule{Threshold: time.Second},
+			samples: samplesOf(now, 2000, 900),
+		},
+		{
+			name:    "latest over threshold",
+			rule:    LatencyRule{Threshold: time.Second},
+			samples: samplesOf(now, 100, 1500),
+			want:    &LatencyInfo{Observed: 1500 * time.Millisecond, Threshold: time.Second},
+		},
+		{
+			name:    "percentile over threshold",
+			rule:    LatencyRule{Threshold: time.Second, Percentile: 50, Window: 10 * time.Minute},
+			samples: samplesOf(now, 1200, 1300, 100),
+			want:    &LatencyInfo{Observed: 1200 * time.Millisecond, Percentile: 50, Threshold: time.Second},
+		},
+		{
+			name:    "default percentile",
+			rule:    LatencyRule{Threshold: time.Second, Window: 10 * time.Minute},
+			samples: samplesOf(now, 100, 100, 100, 100, 100, 100, 100, 100, 100, 1100),
+			want:    &LatencyInfo{Observed: 1100 * time.Millisecond, Percentile: 95, Threshold: time.Second},
+		},
+		{
+			name:    "slow samples outside window",
+			rule:    LatencyRule{Threshold: time.Second, Percentile: 100, Window: 90 * time.Second},
+			samples: samplesOf(now, 5000, 5000, 100, 100),
+		},
+		{
+			name:    "empty window",
+			rule:    LatencyRule{Threshold: time.Second, Window: time.Minute},
+			samples: []latencySample{{at: now.Add(-time.Hour), duration: 5 * time.Second}},
+		},
+		{
+			name:    "over baseline",
+			rule:    LatencyRule{Baseline: time.Hour, Factor: 3},
+			samples: samplesOf(now, append(steady, 400)...),
+			want:    &LatencyInfo{Observed: 400 * time.Millisecond, Baseline: 100 * time.Millisecond, Factor: 3},
+		},
+		{
+			name:    "within baseline",
+			rule:    LatencyRule{Baseline: time.Hour, Factor: 3},
+			samples: samplesOf(now, append(steady, 250)...),
+		},
+		{
+			name:    "too few baseline samples",
+			rule:    LatencyRule{Baseline: time.Hour, Factor: 3},
+			samples: samplesOf(now, 100, 100, 100, 1000),
+		},
+		{
+			name:    "threshold checked before baseline",
+			rule:    LatencyRule{Threshold: 300 * time.Millisecond, Baseline: time.Hour, Factor: 3},
+			samples: samplesOf(now, append(steady, 400)...),
+			want:    &LatencyInfo{Observed: 400 * time.Millisecond, Threshold: 300 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evaluateLatency(tt.rule, tt.samples, now)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil:
+				t.Errorf("evaluateLatency() = %+v, want %+v", got, tt.want)
+			case *got != *tt.want:
+				t.Errorf("evaluateLatency() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []time.Duration{5, 1, 4, 2, 3, 6, 8, 7, 10, 9}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, 1},
+		{10, 1},
+		{50, 5},
+		{90, 9},
+		{95, 10},
+		{100, 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Errorf("percentile(p%g) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPruneSamples(t *testing.T) {
+	now := time.Now()
+	samples := samplesOf(now, 1, 2, 3, 4)
+
+	if got := pruneSamples(samples, now.Add(-90*time.Second)); len(got) != 2 || got[0].duration != 3*time.Millisecond {
+		t.Errorf("pruneSamples() kept %v, want the last two", got)
+	}
+	if got := pruneSamples(samples, now.Add(time.Hour)); len(got) != 1 || got[0].duration != 4*time.Millisecond {
+		t.Errorf("pruneSamples() kept %v, want only the latest", got)
+	}
+}
+
+func TestRecordLatencyCooldown(t *testing.T) {
+	tests := []struct {
+		name     string
+		cooldown time.Duration // Alerting cooldown
+		rule     time.Duration // Rule cooldown
+		want     int
+	}{
+		{name: "rule cooldown", rule: time.Hour, want: 1},
+		{name: "falls back to alerting cooldown", cooldown: time.Hour, want: 1},
+		{name: "rule cooldown wins", cooldown: time.Hour, rule: time.Nanosecond, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			a := New(Config{
+				Enabled:      true,
+				Cooldown:     tt.cooldown,
+				Routes:       []Route{{Steps: []EscalationStep{{Notifiers: []string{"rec"}}}}},
+				LatencyRules: []LatencyRule{{Threshold: time.Millisecond, Cooldown: tt.rule}},
+			})
+			a.notifiers["rec"] = rec
+
+			for i := 0; i < 3; i++ {
+				time.Sleep(time.Millisecond)
+				if err := a.RecordLatency("https://api.example.com", time.Second); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(rec.events) != tt.want {
+				t.Errorf("sent %d latency alerts, want %d", len(rec.events), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package alerter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventKind identifies what an Event reports
+type EventKind string
+
+const (
+	EventFailure      EventKind = "failure"
+	EventRecovery     EventKind = "recovery"
+	EventCertExpiring EventKind = "cert_expiring"
+	EventLatency      EventKind = "latency"
+)
+
+// Event is a single notification handed to a Notifier
+type Event struct {
+	Kind       EventKind
+	Alert      Alert
+	Dependents []string     // Targets grouped under this incident
+	Step       int          // Escalation step reached (0-based)
+	Reminder   bool         // Repeat of an already delivered step
+	Cert       *CertInfo    // Set for EventCertExpiring
+	Latency    *LatencyInfo // Set for EventLatency
+	Severity   string       // "warning" or "critical" for threshold alerts
+}
+
+// Notifier delivers events to an external system. More than one event is
+// passed when alerts are grouped into a digest.
+type Notifier interface {
+	Notify(events []Event) error
+}
+
+// NotifierConfig selects and configures a notifier. Exactly one field
+// should be set.
+type NotifierConfig struct {
+	Webhook   *WebhookConfig
+	Email     *EmailConfig
+	PagerDuty *PagerDutyConfig
+	Command   *CommandConfig
+}
+
+// EmailConfig for SMTP delivery
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// PagerDutyConfig for the PagerDuty Events API v2
+type PagerDutyConfig struct {
+	RoutingKey string
+	Severity   string
+}
+
+func newNotifier(cfg NotifierConfig, client *http.Client) (Notifier, error) {
+	switch {
+	case cfg.Webhook != nil:
+		if cfg.Webhook.URL == "" {
+			return nil, fmt.Errorf("webhook url is required")
+		}
+		return &webhookNotifier{config: *cfg.Webhook, client: client}, nil
+	case cfg.Email != nil:
+		if cfg.Email.Host == "" || len(cfg.Email.To) == 0 {
+			return nil, fmt.Errorf("email host and recipients are required")
+		}
+		return &emailNotifier{config: *cfg.Email}, nil
+	case cfg.PagerDuty != nil:
+		if cfg.PagerDuty.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty routing key is required")
+		}
+		return &pagerDutyNotifier{config: *cfg.PagerDuty, client: client}, nil
+	case cfg.Command != nil:
+		if cfg.Command.Command == "" {
+			return nil, fmt.Errorf("command is required")
+		}
+		return &commandNotifier{config: *cfg.Command}, nil
+	}
+	return nil, fmt.Errorf("no notifier type configured")
+}
+
+// title returns a short human readable headline for the event
+func (e Event) title() string {
+	switch {
+	case e.Kind == EventRecovery:
+		return "✅ GoPunch Recovery"
+	case e.Kind == EventCertExpiring && e.Severity == SeverityCritical:
+		return "🔴 GoPunch Certificate Expiring (critical)"
+	case e.Kind == EventCertExpiring:
+		return "⚠️ GoPunch Certificate Expiring"
+	case e.Kind == EventLatency:
+		return "🐢 GoPunch Latency Degraded"
+	case e.Reminder:
+		return "🔁 GoPunch Reminder"
+	case e.Step > 0:
+		return fmt.Sprintf("🚨 GoPunch Alert (escalation %d)", e.Step+1)
+	}
+	return "🚨 GoPunch Alert"
+}
+
+// lines returns the event details as "Label: value" pairs
+func (e Event) lines() [][2]string {
+	lines := [][2]string{{"URL", e.Alert.URL}}
+	switch {
+	case e.Kind == EventCertExpiring && e.Cert != nil:
+		lines = append(lines,
+			[2]string{"Expires", fmt.Sprintf("%s (%d days left)", e.Cert.NotAfter.Format("2006-01-02"), e.Cert.DaysLeft)},
+			[2]string{"Subject", e.Cert.Subject},
+			[2]string{"Issuer", e.Cert.Issuer},
+			[2]string{"Serial", e.Cert.Serial},
+		)
+	case e.Kind == EventLatency && e.Latency != nil:
+		lines = append(lines, [2]string{"Latency", e.Latency.Reason()})
+	case e.Kind == EventRecovery:
+		lines = append(lines, [2]string{"Status", "Back online"})
+	case e.Alert.Error != "":
+		lines = append(lines, [2]string{"Error", e.Alert.Error})
+	default:
+		lines = append(lines, [2]string{"Status", e.Alert.Status})
+	}
+	if len(e.Dependents) > 0 {
+		label := fmt.Sprintf("Affected dependents (%d)", len(e.Dependents))
+		if e.Kind == EventRecovery {
+			label = fmt.Sprintf("Dependents in this incident (%d)", len(e.Dependents))
+		}
+		lines = append(lines, [2]string{label, "\n" + strings.Join(e.Dependents, "\n")})
+	}
+	return lines
+}
+
+// digestTitle summarizes a batch of events in one headline
+func digestTitle(events []Event) string {
+	if len(events) == 1 {
+		return events[0].title()
+	}
+	down, up, other := 0, 0, 0
+	for _, e := range events {
+		switch e.Kind {
+		case EventFailure:
+			down++
+		case EventRecovery:
+			up++
+		default:
+			other++
+		}
+	}
+	var parts []string
+	if down > 0 {
+		parts = append(parts, fmt.Sprintf("%d down", down))
+	}
+	if up > 0 {
+		parts = append(parts, fmt.Sprintf("%d recovered", up))
+	}
+	if other > 0 {
+		parts = append(parts, fmt.Sprintf("%d warnings", other))
+	}
+	icon := "🚨"
+	if down == 0 && other == 0 {
+		icon = "✅"
+	}
+	return fmt.Sprintf("%s GoPunch Digest: %s", icon, strings.Join(parts, ", "))
+}
+
+// digestLine describes one event of a digest on a single line
+func (e Event) digestLine() string {
+	if e.Kind == EventRecovery {
+		return fmt.Sprintf("🟢 %s: Back online", e.Alert.URL)
+	}
+	if e.Kind == EventCertExpiring && e.Cert != nil {
+		return fmt.Sprintf("🟠 %s: certificate expires in %d days (%s)", e.Alert.URL, e.Cert.DaysLeft, e.Severity)
+	}
+	if e.Kind == EventLatency && e.Latency != nil {
+		return fmt.Sprintf("🐢 %s: %s", e.Alert.URL, e.Latency.Reason())
+	}
+	detail := e.Alert.Error
+	if detail == "" {
+		detail = e.Alert.Status
+	}
+	line := fmt.Sprintf("🔴 %s: %s", e.Alert.URL, detail)
+	if len(e.Dependents) > 0 {
+		line += fmt.Sprintf(" (+%d dependents)", len(e.Dependents))
+	}
+	return line
+}
+
+// hasKind reports whether any event in the batch is of the given kind
+func hasKind(events []Event, kind EventKind) bool {
+	for _, e := range events {
+		if e.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCritical reports whether any threshold event in the batch is critical
+func hasCritical(events []Event) bool {
+	for _, e := range events {
+		if e.Severity == SeverityCritical {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Event) timestamp() time.Time {
+	if e.Alert.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return e.Alert.Timestamp
+}
+
+type webhookNotifier struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+func (w *webhookNotifier) Notify(events []Event) error {
+	var description []string
+	if len(events) == 1 {
+		for _, l := range events[0].lines() {
+			description = append(description, fmt.Sprintf("**%s:** %s", l[0], l[1]))
+		}
+	} else {
+		for _, e := range events {
+			description = append(description, e.digestLine())
+		}
+	}
+
+	color := 65280 // Green
+	switch {
+	case hasKind(events, EventFailure) || hasCritical(events):
+		color = 16711680 // Red
+	case hasKind(events, EventCertExpiring) || hasKind(events, EventLatency):
+		color = 16753920 // Orange
+	}
+
+	// Discord webhook format
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{
+			{
+				"title":       digestTitle(events),
+				"description": strings.Join(description, "\n"),
+				"color":       color,
+				"timestamp":   events[len(events)-1].timestamp().Format(time.RFC3339),
+				"footer": map[string]string{
+					"text": "GoPunch Monitoring",
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	method := w.config.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	req, err := http.NewRequest(method, w.config.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type emailNotifier struct {
+	config EmailConfig
+}
+
+func (m *emailNotifier) Notify(events []Event) error {
+	port := m.config.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(port))
+
+	from := m.config.From
+	if from == "" {
+		from = m.config.Username
+	}
+
+	var body strings.Builder
+	subject := digestTitle(events)
+	if len(events) == 1 {
+		subject += ": " + events[0].Alert.URL
+		for _, l := range events[0].lines() {
+			fmt.Fprintf(&body, "%s: %s\r\n", l[0], l[1])
+		}
+	} else {
+		for _, e := range events {
+			fmt.Fprintf(&body, "%s\r\n", e.digestLine())
+		}
+	}
+	fmt.Fprintf(&body, "Time: %s\r\n", events[len(events)-1].timestamp().Format(time.RFC1123))
+
+	// The subject carries emoji, so it needs RFC 2047 encoding
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n%s",
+		from, strings.Join(m.config.To, ", "), mime.QEncoding.Encode("utf-8", subject), body.String())
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	if err := smtp.SendMail(addr, auth, from, m.config.To, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+type pagerDutyNotifier struct {
+	config PagerDutyConfig
+	client *http.Client
+}
+
+// Notify sends one PagerDuty event per target, since incidents are
+// deduplicated by target rather than by digest
+func (p *pagerDutyNotifier) Notify(events []Event) error {
+	var errs []error
+	for _, e := range events {
+		if err := p.send(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *pagerDutyNotifier) send(event Event) error {
+	action := "trigger"
+	if event.Kind == EventRecovery {
+		action = "resolve"
+	}
+
+	severity := p.config.Severity
+	if severity == "" {
+		severity = "critical"
+	}
+	if event.Severity != "" {
+		severity = event.Severity
+	}
+
+	dedupKey := "gopunch:" + event.Alert.URL
+	summary := fmt.Sprintf("%s is down: %s", event.Alert.URL, event.Alert.Error)
+	if event.Kind == EventCertExpiring && event.Cert != nil {
+		dedupKey = "gopunch:cert:" + event.Cert.Serial
+		summary = fmt.Sprintf("Certificate for %s expires in %d days", event.Alert.URL, event.Cert.DaysLeft)
+	}
+	if event.Kind == EventLatency && event.Latency != nil {
+		dedupKey = "gopunch:latency:" + event.Alert.URL
+		summary = fmt.Sprintf("%s is slow: %s", event.Alert.URL, event.Latency.Reason())
+	}
+
+	var details []string
+	for _, l := range event.lines() {
+		details = append(details, fmt.Sprintf("%s: %s", l[0], l[1]))
+	}
+
+	payload := map[string]interface{}{
+		"routing_key":  p.config.RoutingKey,
+		"event_action": action,
+		"dedup_key":    dedupKey,
+	}
+	if action == "trigger" {
+		payload["payload"] = map[string]interface{}{
+			"summary":        summary,
+			"source":         event.Alert.URL,
+			"severity":       severity,
+			"timestamp":      event.timestamp().Format(time.RFC3339),
+			"custom_details": strings.Join(details, "\n"),
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pagerduty payload: %w", err)
+	}
+
+	resp, err := p.client.Post(pagerDutyEventsURL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to send pagerduty event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("pagerduty returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// allIPsSchemes are the target schemes that can be pinned to one address
+// through Options.Resolve
+var allIPsSchemes = map[string]bool{"http": true, "https": true, "tcp": true, "ssl": true}
+
+// familySchemes are the target schemes that honour Options.IPFamily
+var familySchemes = map[string]bool{"http": true, "https": true, "tcp": true, "ssl": true, "dns": true}
+
+// parseIPFamily normalizes an address family setting to "", "4", "6" or
+// "both"
+func parseIPFamily(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "any", "auto":
+		return "", nil
+	case "4", "v4", "ipv4":
+		return "4", nil
+	case "6", "v6", "ipv6":
+		return "6", nil
+	case "both", "dual":
+		return "both", nil
+	}
+	return "", fmt.Errorf("unknown IP family %q, want 4, 6 or both", s)
+}
+
+// checkDualStack checks the target once over IPv4 and once over IPv6 and
+// passes only when both families do
+func checkDualStack(target string, opts Options) Result {
+	result := Result{URL: target, Families: make([]Result, 2)}
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i, family := range []string{"4", "6"} {
+		wg.Add(1)
+		go func(idx int, family string) {
+			defer wg.Done()
+			single := opts
+			single.IPFamily = family
+			res := checkTarget(target, single)
+			res.Family = "IPv" + family
+			result.Families[idx] = res
+		}(i, family)
+	}
+	wg.Wait()
+	result.Duration = time.Since(start)
+
+	up := 0
+	var failures []string
+	for _, res := range result.Families {
+		if res.Success && res.Error == nil {
+			up++
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", res.Family, failureReason(res)))
+	}
+
+	result.Info = fmt.Sprintf("%d/2 families up", up)
+	result.Success = up == len(result.Families)
+	if !result.Success {
+		result.Error = errors.New(strings.Join(failures, "; "))
+	}
+	return result
+}
+
+// checkAllIPs resolves the target's host and checks every address on its
+// own, keeping the original name for the Host header and SNI. The target is
+// up when at least opts.MinHealthy addresses are (all of them when zero).
+func checkAllIPs(target string, opts Options) Result {
+	result := Result{URL: target}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	host := u.Hostname()
+
+	start := time.Now()
+	ips, err := lookupAddresses(host, opts.IPFamily, opts.Timeout)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Errorf("resolving %s: %w", host, err)
+		return result
+	}
+
+	single := opts
+	single.AllIPs = false
+	result.Addresses = make([]Result, len(ips))
+
+	var wg sync.WaitGroup
+	for i, ip := range ips {
+		wg.Add(1)
+		go func(idx int, ip string) {
+			defer wg.Done()
+			pinned := single
+			// Prepended so it wins over any broader override for the host
+			pinned.Resolve = append([]string{fmt.Sprintf("[%s]:*:[%s]", host, ip)}, single.Resolve...)
+			res := checkTarget(target, pinned)
+			res.Address = ip
+			result.Addresses[idx] = res
+		}(i, ip)
+	}
+	wg.Wait()
+	result.Duration = time.Since(start)
+
+	needed := opts.MinHealthy
+	if needed <= 0 {
+		needed = len(ips)
+	}
+
+	healthy := 0
+	var failures []string
+	for _, res := range result.Addresses {
+		if res.Success && res.Error == nil {
+			healthy++
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", res.Address, failureReason(res)))
+	}
+
+	result.Info = fmt.Sprintf("%d/%d IPs up", healthy, len(ips))
+	result.Success = healthy >= needed
+	if !result.Success {
+		result.Error = fmt.Errorf("%d of %d addresses healthy, need %d (%s)",
+			healthy, len(ips), needed, strings.Join(failures, "; "))
+	}
+	return result
+}
+
+// lookupAddresses returns every A and AAAA record for host, only one kind
+// when family is "4" or "6", or host itself when it is already an IP address
+func lookupAddresses(host, family string, timeout time.Duration) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	network := "ip"
+	if family == "4" || family == "6" {
+		network += family
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	addrs, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.String()
+	}
+	return ips, nil
+}
+
+// failureReason describes why a result counts as down
+func failureReason(res Result) string {
+	if res.Error != nil {
+		return res.Error.Error()
+	}
+	if res.Status != "" {
+		return res.Status
+	}
+	if res.Info != "" {
+		return res.Info
+	}
+	return "failed"
+}
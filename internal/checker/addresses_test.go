@@ -0,0 +1,54 @@
+package checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllIPsWithoutScheme(t *testing.T) {
+	// Nothing listens on port 1, so the address is checked and fails fast
+	res := checkTarget("127.0.0.1:1", Options{AllIPs: true, Timeout: time.Second})
+	if res.URL != "https://127.0.0.1:1" {
+		t.Errorf("URL = %q, want https://127.0.0.1:1", res.URL)
+	}
+	if len(res.Addresses) != 1 || res.Addresses[0].Address != "127.0.0.1" {
+		t.Fatalf("Addresses = %+v, want one result for 127.0.0.1", res.Addresses)
+	}
+	if res.Success {
+		t.Error("closed port reported as up")
+	}
+}
+
+func TestDualStackWithoutScheme(t *testing.T) {
+	res := checkTarget("localhost:1", Options{IPFamily: "both", Timeout: time.Second})
+	if len(res.Families) != 2 {
+		t.Fatalf("Families = %+v, want IPv4 and IPv6 results", res.Families)
+	}
+	for i, family := range []string{"IPv4", "IPv6"} {
+		if res.Families[i].Family != family {
+			t.Errorf("Families[%d] = %q, want %q", i, res.Families[i].Family, family)
+		}
+	}
+}
+
+func TestParseIPFamily(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "any", want: ""},
+		{in: "4", want: "4"},
+		{in: "IPv6", want: "6"},
+		{in: " both ", want: "both"},
+		{in: "dual", want: "both"},
+		{in: "5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseIPFamily(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseIPFamily(%q) = %q, %v, want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
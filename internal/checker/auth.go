@@ -0,0 +1,170 @@
+package checker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth configures how HTTP checks authenticate
+type Auth struct {
+	Type         string // basic, bearer or oauth2
+	Username     string
+	Password     string
+	Token        string // Static bearer token
+	TokenURL     string // OAuth2 token endpoint
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// tokenRefreshMargin is how long before expiry a cached token is renewed
+const tokenRefreshMargin = 30 * time.Second
+
+type cachedToken struct {
+	mu     sync.Mutex
+	value  string
+	expiry time.Time // Zero when the server gave no lifetime
+}
+
+// tokens caches OAuth2 access tokens across checks, keyed by endpoint,
+// client and scopes
+var tokens = struct {
+	sync.Mutex
+	entries map[string]*cachedToken
+}{entries: make(map[string]*cachedToken)}
+
+// tokenClients keeps the clients that fetch OAuth2 tokens, keyed by proxy
+// settings and timeout
+var tokenClients = struct {
+	sync.Mutex
+	entries map[string]*http.Client
+}{entries: make(map[string]*http.Client)}
+
+// tokenClient returns the client for token requests. It only shares the
+// proxy settings with the target: the endpoint's certificate is always
+// verified, and address overrides and forced HTTP versions are left out so
+// that client credentials only ever go to the token URL itself.
+func tokenClient(opts Options) (*http.Client, error) {
+	proxy, err := httpProxy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s|%s|%s", opts.Proxy, strings.Join(opts.NoProxy, ","), opts.Timeout)
+	tokenClients.Lock()
+	defer tokenClients.Unlock()
+	if client, ok := tokenClients.entries[key]; ok {
+		return client, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	client := &http.Client{Timeout: opts.Timeout, Transport: transport}
+	tokenClients.entries[key] = client
+	return client, nil
+}
+
+// authorization returns the Authorization header value for auth, fetching
+// an OAuth2 token with client (see tokenClient) if needed
+func authorization(auth *Auth, client *http.Client) (string, error) {
+	switch strings.ToLower(auth.Type) {
+	case "basic":
+		creds := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+		return "Basic " + creds, nil
+	case "bearer":
+		return "Bearer " + auth.Token, nil
+	case "oauth2":
+		token, err := oauth2Token(auth, client)
+		if err != nil {
+			return "", fmt.Errorf("oauth2 token: %w", err)
+		}
+		return "Bearer " + token, nil
+	}
+	return "", fmt.Errorf("unknown auth type %q", auth.Type)
+}
+
+func (a *Auth) cacheKey() string {
+	return a.TokenURL + "|" + a.ClientID + "|" + strings.Join(a.Scopes, " ")
+}
+
+func tokenEntry(auth *Auth) *cachedToken {
+	tokens.Lock()
+	defer tokens.Unlock()
+	key := auth.cacheKey()
+	entry, ok := tokens.entries[key]
+	if !ok {
+		entry = &cachedToken{}
+		tokens.entries[key] = entry
+	}
+	return entry
+}
+
+// oauth2Token returns a cached access token, or requests a new one with
+// the client credentials grant when it is missing or about to expire
+func oauth2Token(auth *Auth, client *http.Client) (string, error) {
+	entry := tokenEntry(auth)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.value != "" && (entry.expiry.IsZero() || time.Until(entry.expiry) > tokenRefreshMargin) {
+		return entry.value, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+
+	req, err := http.NewRequest("POST", auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "GoPunch/2.0")
+	req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access_token")
+	}
+
+	entry.value = token.AccessToken
+	entry.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		entry.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return entry.value, nil
+}
+
+// invalidateToken drops a cached OAuth2 token, e.g. after the target
+// rejected it, so the next check fetches a fresh one
+func invalidateToken(auth *Auth) {
+	entry := tokenEntry(auth)
+	entry.mu.Lock()
+	entry.value = ""
+	entry.mu.Unlock()
+}
@@ -0,0 +1,160 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer issues numbered tokens that expire after expiresIn seconds
+// and counts how many it handed out
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if r.Method != "POST" || id != "gopunch" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := issued.Add(1)
+		fmt.Fprintf(w, `{"access_token":"tok-%d","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+func oauth2Auth(tokenURL string) *Auth {
+	return &Auth{Type: "oauth2", TokenURL: tokenURL, ClientID: "gopunch", ClientSecret: "s3cret"}
+}
+
+func TestAuthorization(t *testing.T) {
+	tests := []struct {
+		auth *Auth
+		want string
+	}{
+		{&Auth{Type: "basic", Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz"},
+		{&Auth{Type: "Bearer", Token: "abc"}, "Bearer abc"},
+	}
+	for _, tt := range tests {
+		got, err := authorization(tt.auth, nil)
+		if err != nil || got != tt.want {
+			t.Errorf("authorization(%s) = %q, %v, want %q", tt.auth.Type, got, err, tt.want)
+		}
+	}
+	if _, err := authorization(&Auth{Type: "digest"}, nil); err == nil {
+		t.Error("unknown auth type accepted")
+	}
+}
+
+func TestOAuth2TokenCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn int
+		wantFetch int32 // Token requests for two checks in a row
+	}{
+		{name: "reused until expiry", expiresIn: 3600, wantFetch: 1},
+		{name: "reused without lifetime", expiresIn: 0, wantFetch: 1},
+		{name: "refreshed within margin", expiresIn: int(tokenRefreshMargin/time.Second) - 1, wantFetch: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, issued := tokenServer(t, tt.expiresIn)
+			auth := oauth2Auth(srv.URL)
+
+			first, err := oauth2Token(auth, srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := oauth2Token(auth, srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := issued.Load(); got != tt.wantFetch {
+				t.Errorf("fetched %d tokens, want %d", got, tt.wantFetch)
+			}
+			if (first == second) != (tt.wantFetch == 1) {
+				t.Errorf("tokens %q and %q, want them reused: %v", first, second, tt.wantFetch == 1)
+			}
+		})
+	}
+}
+
+func TestOAuth2TokenErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/denied":
+			w.WriteHeader(http.StatusForbidden)
+		case "/empty":
+			fmt.Fprint(w, `{"token_type":"bearer"}`)
+		default:
+			fmt.Fprint(w, "not json")
+		}
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/denied", "/empty", "/garbage"} {
+		if token, err := oauth2Token(oauth2Auth(srv.URL+path), srv.Client()); err == nil {
+			t.Errorf("%s: got token %q, want an error", path, token)
+		}
+	}
+}
+
+func TestOAuth2TokenInvalidatedOn401(t *testing.T) {
+	tokenSrv, issued := tokenServer(t, 3600)
+	var seen []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer target.Close()
+
+	opts := Options{Timeout: 5 * time.Second, Auth: oauth2Auth(tokenSrv.URL)}
+	for i := 0; i < 2; i++ {
+		if res := checkTarget(target.URL, opts); res.Success {
+			t.Fatal("401 reported as up")
+		}
+	}
+
+	if got := issued.Load(); got != 2 {
+		t.Errorf("fetched %d tokens, want a new one after the 401", got)
+	}
+	if len(seen) != 2 || seen[0] != "Bearer tok-1" || seen[1] != "Bearer tok-2" {
+		t.Errorf("target saw %q, want tok-1 then tok-2", seen)
+	}
+}
+
+func TestTokenClientIgnoresTargetSettings(t *testing.T) {
+	srv, _ := tokenServer(t, 3600)
+
+	// Each of these would break or misdirect the token request if the
+	// target's client were used for it
+	opts := Options{
+		Timeout:     5 * time.Second,
+		Insecure:    true,
+		ConnectTo:   []string{"::127.0.0.1:1"},
+		HTTPVersion: "2",
+		KeepAlive:   true,
+	}
+	client, err := tokenClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr := client.Transport.(*http.Transport); tr.TLSClientConfig != nil && tr.TLSClientConfig.InsecureSkipVerify {
+		t.Error("token client skips certificate verification")
+	}
+	if _, err := oauth2Token(oauth2Auth(srv.URL+"/ignores"), client); err != nil {
+		t.Errorf("token request failed: %v", err)
+	}
+
+	again, err := tokenClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != client {
+		t.Error("token client was not cached")
+	}
+}
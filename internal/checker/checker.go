@@ -3,16 +3,18 @@ package checker
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/net/http/httpproxy"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/TheRemyyy/gopunch/internal/health"
@@ -28,6 +30,41 @@ type Options struct {
 	FollowRedirects bool
 	ExpectedCodes   []int
 	Retries         int
+	Send            string // Payload for ws/tcp/udp probes (see health.ParsePayload)
+	Match           string // Expected reply for ws/tcp/udp probes (see health.ParseMatcher)
+	ReadTimeout     time.Duration
+	AcceptNoReply   bool     // udp:// targets count as up when no reply arrives
+	ClientCert      string   // PEM client certificate for mutual TLS
+	ClientKey       string   // PEM private key for ClientCert
+	CACert          string   // PEM CA bundle to verify servers against
+	ServerName      string   // SNI and verification name override
+	MinTLSVersion   string   // e.g. "1.2"
+	Auth            *Auth    // Credentials for HTTP checks
+	Steps           []Step   // Multi-step HTTP flow run instead of a single request
+	Proxy           string   // http://, https:// or socks5:// proxy for HTTP, TCP and SSL checks
+	NoProxy         []string // Hosts, domains or CIDRs that bypass Proxy
+	Resolve         []string // host:port:address overrides, as in curl --resolve
+	ConnectTo       []string // host:port:connect-host:connect-port overrides, as in curl --connect-to
+	AllIPs          bool     // Check every resolved address of http(s), tcp and ssl targets
+	MinHealthy      int      // Addresses that must be up with AllIPs; all of them when zero
+	IPFamily        string   // "4", "6" or "both" for http(s), tcp, ssl and dns targets; any when empty
+	HTTPVersion     string   // "1.1", "2" or "3" to force a protocol; negotiated when empty
+	MaxRedirects    int      // Most redirects to follow; 10 when zero
+	RequireHTTPS    bool     // Fail unless redirects stay on https and end there
+	FinalURL        string   // URL the redirect chain must end at
+	KeepAlive       bool     // Reuse connections between checks of a target ("warm" probes)
+}
+
+// Target is a URL with its own check options
+type Target struct {
+	URL     string
+	Options Options
+}
+
+// Timing is a named phase of a check
+type Timing struct {
+	Name     string
+	Duration time.Duration
 }
 
 // Result represents the outcome of a check
@@ -42,42 +79,99 @@ type Result struct {
 	Success    bool
 	Error      error
 	Retries    int
+	SSL        *health.SSLResult // Certificate details for ssl:// checks
+	Timings    []Timing          // Breakdown of Duration, if the check has phases
+	Answers    []string          // DNS answers for dns:// checks
+	Address    string            // IP checked, for per-address results
+	Addresses  []Result          // Per-address results when Options.AllIPs is set
+	Family     string            // "IPv4" or "IPv6", for per-family results
+	Families   []Result          // Per-family results when Options.IPFamily is "both"
+	Protocol   string            // Negotiated HTTP protocol, e.g. "HTTP/2.0"
+	Redirects  []Hop             // Every response from the target to the final URL, if redirected
+	Reused     bool              // The request went over a connection kept from an earlier check
 }
 
 // CheckURLs performs concurrent health checks
 func CheckURLs(urls []string, opts Options, concurrency int) []Result {
-	results := make([]Result, len(urls))
+	targets := make([]Target, len(urls))
+	for i, u := range urls {
+		targets[i] = Target{URL: u, Options: opts}
+	}
+	return CheckTargets(targets, concurrency)
+}
+
+// CheckTargets performs concurrent health checks, each with its own options
+func CheckTargets(targets []Target, concurrency int) []Result {
+	results := make([]Result, len(targets))
 	sem := semaphore.NewWeighted(int64(concurrency))
 	var wg sync.WaitGroup
 
-	for i, u := range urls {
+	for i, t := range targets {
 		wg.Add(1)
-		go func(idx int, target string) {
+		go func(idx int, t Target) {
 			defer wg.Done()
 			_ = sem.Acquire(context.Background(), 1)
 			defer sem.Release(1)
 
-			// Detect scheme
-			if strings.HasPrefix(target, "tcp://") {
-				results[idx] = checkTCP(target, opts)
-			} else if strings.HasPrefix(target, "dns://") {
-				results[idx] = checkDNS(target, opts)
-			} else if strings.HasPrefix(target, "ssl://") {
-				results[idx] = checkSSL(target, opts)
-			} else {
-				// Default to HTTP
-				if !strings.HasPrefix(target, "http") {
-					target = "https://" + target
-				}
-				results[idx] = checkHTTP(target, opts)
-			}
-		}(i, u)
+			results[idx] = checkTarget(t.URL, t.Options)
+		}(i, t)
 	}
 
 	wg.Wait()
 	return results
 }
 
+func checkTarget(target string, opts Options) Result {
+	family, err := parseIPFamily(opts.IPFamily)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+	opts.IPFamily = family
+	if opts.HTTPVersion, err = parseHTTPVersion(opts.HTTPVersion); err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	// Targets without a scheme are HTTPS URLs
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+
+	if family == "both" {
+		if u, err := url.Parse(target); err == nil && familySchemes[u.Scheme] {
+			return checkDualStack(target, opts)
+		}
+		opts.IPFamily = ""
+	}
+
+	if opts.AllIPs {
+		if u, err := url.Parse(target); err == nil && allIPsSchemes[u.Scheme] {
+			return checkAllIPs(target, opts)
+		}
+	}
+
+	if len(opts.Steps) > 0 {
+		return checkSteps(target, opts)
+	}
+
+	// Detect scheme
+	if strings.HasPrefix(target, "tcp://") {
+		return checkTCP(target, opts)
+	} else if strings.HasPrefix(target, "udp://") {
+		return checkUDP(target, opts)
+	} else if strings.HasPrefix(target, "dns://") {
+		return checkDNS(target, opts)
+	} else if strings.HasPrefix(target, "ssl://") {
+		return checkSSL(target, opts)
+	} else if strings.HasPrefix(target, "grpc://") || strings.HasPrefix(target, "grpcs://") {
+		return checkGRPC(target, opts)
+	} else if strings.HasPrefix(target, "ws://") || strings.HasPrefix(target, "wss://") {
+		return checkWebSocket(target, opts)
+	}
+
+	// Default to HTTP
+	return checkHTTP(target, opts)
+}
+
 func checkTCP(target string, opts Options) Result {
 	u, err := url.Parse(target)
 	if err != nil {
@@ -91,27 +185,106 @@ func checkTCP(target string, opts Options) Result {
 		port, _ = strconv.Atoi(portStr)
 	}
 
-	res := health.CheckTCP(host, port, opts.Timeout)
+	send, err := health.ParsePayload(opts.Send)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+	expect, err := health.ParseMatcher(opts.Match)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	dial, err := targetDialer(opts)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	res := health.CheckTCPProbe(host, port, opts.Timeout, health.TCPProbe{
+		Send:        send,
+		Expect:      expect,
+		ReadTimeout: opts.ReadTimeout,
+		Dial:        dial,
+	})
 
 	result := Result{
 		URL:      target,
 		Duration: res.Duration,
-		Success:  res.Open,
+		Success:  res.Open && res.Error == nil,
 		Error:    res.Error,
+		Size:     int64(len(res.Response)),
 	}
 
-	if res.Open {
+	if len(send) > 0 || expect != nil {
+		result.Timings = []Timing{
+			{Name: "connect", Duration: res.ConnectTime},
+			{Name: "response", Duration: res.Duration - res.ConnectTime},
+		}
+	}
+	if res.Matched {
+		result.Info = "Match"
+	} else if res.Open && res.Error == nil {
 		result.Info = "Open"
 	}
 	return result
 }
 
+func checkUDP(target string, opts Options) Result {
+	u, err := url.Parse(target)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return Result{URL: target, Error: fmt.Errorf("udp target needs a port")}
+	}
+
+	send, err := health.ParsePayload(opts.Send)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+	expect, err := health.ParseMatcher(opts.Match)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	res := health.CheckUDP(u.Hostname(), port, opts.Timeout, health.UDPProbe{
+		Send:          send,
+		Expect:        expect,
+		ReadTimeout:   opts.ReadTimeout,
+		AcceptNoReply: opts.AcceptNoReply,
+	})
+
+	result := Result{
+		URL:      target,
+		Duration: res.Duration,
+		Success:  res.Up,
+		Error:    res.Error,
+		Size:     int64(len(res.Response)),
+	}
+
+	if res.Up {
+		result.Info = "Reply"
+		if expect != nil {
+			result.Info = "Match"
+		}
+		if !res.Replied {
+			result.Info = "No reply"
+		}
+	}
+	return result
+}
+
 func checkDNS(target string, opts Options) Result {
+	original := target
+	var query url.Values
+
 	u, err := url.Parse(target)
 	if err != nil {
 		// handle "dns://hostname" or regular hostname
 		target = strings.TrimPrefix(target, "dns://")
 	} else {
+		query = u.Query()
 		target = u.Hostname()
 		if target == "" {
 			target = u.Path // Handle dns://hostname case if simple
@@ -121,6 +294,46 @@ func checkDNS(target string, opts Options) Result {
 	// If parse failed somewhat or target still has scheme
 	target = strings.TrimPrefix(target, "dns://")
 
+	// A forced address family picks the record type when none is given
+	recordType := query.Get("type")
+	if recordType == "" && opts.IPFamily == "4" {
+		recordType = "A"
+	} else if recordType == "" && opts.IPFamily == "6" {
+		recordType = "AAAA"
+	}
+
+	// dns://host?type=MX&server=8.8.8.8&expect=mail.example.com
+	if recordType != "" || query.Get("server") != "" || query.Has("expect") {
+		dnsOpts := health.DNSOptions{
+			Type:    recordType,
+			Expect:  splitParam(query["expect"]),
+			Timeout: opts.Timeout,
+		}
+
+		// Several servers compare their answers with each other
+		servers := splitParam(query["server"])
+		if len(servers) > 1 {
+			return compareDNS(original, target, servers, dnsOpts)
+		}
+		if len(servers) == 1 {
+			dnsOpts.Server = servers[0]
+		}
+
+		res := health.CheckDNSRecord(target, dnsOpts)
+
+		result := Result{
+			URL:      original,
+			Duration: res.Duration,
+			Success:  res.Resolved,
+			Error:    res.Error,
+			Answers:  res.Answers,
+		}
+		if res.Resolved {
+			result.Info = fmt.Sprintf("%d %s", len(res.Answers), res.Type)
+		}
+		return result
+	}
+
 	res := health.CheckDNS(target, opts.Timeout)
 
 	result := Result{
@@ -128,6 +341,7 @@ func checkDNS(target string, opts Options) Result {
 		Duration: res.Duration,
 		Success:  res.Resolved,
 		Error:    res.Error,
+		Answers:  res.IPs,
 	}
 
 	if res.Resolved {
@@ -136,6 +350,58 @@ func checkDNS(target string, opts Options) Result {
 	return result
 }
 
+// compareDNS runs the same lookup against every server and fails when a
+// lookup fails or the answers differ
+func compareDNS(original, host string, servers []string, opts health.DNSOptions) Result {
+	cmp := health.CompareDNS(host, servers, opts)
+
+	result := Result{
+		URL:      original,
+		Duration: cmp.Duration,
+		Success:  cmp.Consistent,
+	}
+	for _, res := range cmp.Results {
+		result.Timings = append(result.Timings, Timing{Name: res.Server, Duration: res.Duration})
+	}
+
+	if cmp.Consistent {
+		result.Info = fmt.Sprintf("%d servers agree", len(servers))
+		result.Answers = cmp.Results[0].Answers
+		return result
+	}
+
+	for _, res := range cmp.Results {
+		result.Answers = append(result.Answers, fmt.Sprintf("%s: %s", res.Server, strings.Join(res.Answers, " ")))
+	}
+	for _, res := range cmp.Results {
+		if !res.Resolved {
+			result.Error = fmt.Errorf("%s: %v", res.Server, res.Error)
+			return result
+		}
+	}
+
+	var groups []string
+	for _, g := range cmp.Groups {
+		groups = append(groups, strings.Join(g, ", "))
+	}
+	result.Info = fmt.Sprintf("%d answer sets", len(cmp.Groups))
+	result.Error = fmt.Errorf("answers differ: %s", strings.Join(groups, " | "))
+	return result
+}
+
+// splitParam flattens repeated and comma-separated query values
+func splitParam(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
 func checkSSL(target string, opts Options) Result {
 	u, err := url.Parse(target)
 	if err != nil {
@@ -149,17 +415,160 @@ func checkSSL(target string, opts Options) Result {
 		port, _ = strconv.Atoi(portStr)
 	}
 
-	res := health.CheckSSL(host, port, opts.Timeout)
+	policy, err := parseTLSPolicy(u.Query())
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	// ssl://mail.example.com?starttls=smtp
+	starttls := strings.ToLower(u.Query().Get("starttls"))
+	if starttls != "" {
+		defaultPort, ok := health.StartTLSPorts[starttls]
+		if !ok {
+			return Result{URL: target, Error: fmt.Errorf("unsupported starttls protocol %q", starttls)}
+		}
+		if portStr == "" {
+			port = defaultPort
+		}
+	}
+
+	client, err := tlsClientOptions(opts)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	dial, err := targetDialer(opts)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	res := health.CheckSSLWithOptions(host, port, health.SSLOptions{
+		Timeout:  opts.Timeout,
+		Policy:   policy,
+		StartTLS: starttls,
+		Client:   client,
+		Dial:     dial,
+	})
 
 	result := Result{
 		URL:      target,
 		Duration: res.Duration,
 		Success:  res.Valid,
 		Error:    res.Error,
+		SSL:      &res,
 	}
 
 	if res.Valid {
 		result.Info = fmt.Sprintf("Valid (%d days)", res.DaysLeft)
+		if res.ChainDaysLeft < res.DaysLeft {
+			result.Info = fmt.Sprintf("Valid (%d days, chain %d)", res.DaysLeft, res.ChainDaysLeft)
+		}
+	}
+	return result
+}
+
+// parseTLSPolicy reads policy overrides from ssl:// query parameters:
+// min_tls, min_rsa_bits, allow_weak_sig and ocsp=require
+func parseTLSPolicy(query url.Values) (health.TLSPolicy, error) {
+	policy := health.DefaultTLSPolicy()
+
+	if v := query.Get("min_tls"); v != "" {
+		version, err := health.ParseTLSVersion(v)
+		if err != nil {
+			return policy, err
+		}
+		policy.MinVersion = version
+	}
+	if v := query.Get("min_rsa_bits"); v != "" {
+		bits, err := strconv.Atoi(v)
+		if err != nil {
+			return policy, fmt.Errorf("invalid min_rsa_bits %q", v)
+		}
+		policy.MinRSABits = bits
+	}
+	if v := query.Get("allow_weak_sig"); v != "" {
+		policy.AllowWeakSignatures, _ = strconv.ParseBool(v)
+	}
+	if v := query.Get("ocsp"); v != "" {
+		policy.RequireOCSPStaple = v == "require" || v == "required"
+	}
+	return policy, nil
+}
+
+func checkGRPC(target string, opts Options) Result {
+	u, err := url.Parse(target)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	useTLS := u.Scheme == "grpcs"
+	if v := u.Query().Get("tls"); v != "" {
+		useTLS, _ = strconv.ParseBool(v)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if !useTLS {
+			port = "80"
+		}
+	}
+
+	res := health.CheckGRPC(net.JoinHostPort(u.Hostname(), port), strings.TrimPrefix(u.Path, "/"), health.GRPCOptions{
+		Timeout:  opts.Timeout,
+		TLS:      useTLS,
+		Insecure: opts.Insecure,
+		Metadata: opts.Headers,
+	})
+
+	return Result{
+		URL:      target,
+		Status:   res.Status,
+		Info:     res.Status,
+		Duration: res.Duration,
+		Success:  res.Serving,
+		Error:    res.Error,
+	}
+}
+
+func checkWebSocket(target string, opts Options) Result {
+	send, err := health.ParsePayload(opts.Send)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+	expect, err := health.ParseMatcher(opts.Match)
+	if err != nil {
+		return Result{URL: target, Error: err}
+	}
+
+	res := health.CheckWebSocket(target, health.WebSocketOptions{
+		Timeout:  opts.Timeout,
+		Insecure: opts.Insecure,
+		Headers:  opts.Headers,
+		Send:     send,
+		Expect:   expect,
+	})
+
+	result := Result{
+		URL:      target,
+		Duration: res.Duration,
+		Success:  res.Connected,
+		Error:    res.Error,
+		Size:     int64(len(res.Reply)),
+		Timings:  []Timing{{Name: "handshake", Duration: res.HandshakeTime}},
+	}
+
+	if !res.Connected {
+		result.StatusCode = res.StatusCode
+	}
+	if res.RoundTrip > 0 {
+		result.Timings = append(result.Timings, Timing{Name: "rtt", Duration: res.RoundTrip})
+	}
+	if res.Connected {
+		result.Info = "Connected"
+		if res.RoundTrip > 0 {
+			result.Info = "Reply OK"
+		}
 	}
 	return result
 }
@@ -168,7 +577,14 @@ func checkHTTP(url string, opts Options) Result {
 	var result Result
 	result.URL = url
 
-	client := createClient(opts)
+	client, err := targetClient(url, opts)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if !opts.KeepAlive {
+		defer client.CloseIdleConnections()
+	}
 
 	for attempt := 0; attempt <= opts.Retries; attempt++ {
 		result = doHTTPCheck(url, opts, client)
@@ -186,35 +602,235 @@ func checkHTTP(url string, opts Options) Result {
 	return result
 }
 
-func createClient(opts Options) *http.Client {
+// tlsClientOptions collects the TLS settings from opts
+func tlsClientOptions(opts Options) (health.TLSClientOptions, error) {
+	client := health.TLSClientOptions{
+		CertFile:   opts.ClientCert,
+		KeyFile:    opts.ClientKey,
+		CAFile:     opts.CACert,
+		ServerName: opts.ServerName,
+		Insecure:   opts.Insecure,
+	}
+	if opts.MinTLSVersion != "" {
+		version, err := health.ParseTLSVersion(opts.MinTLSVersion)
+		if err != nil {
+			return client, err
+		}
+		client.MinVersion = version
+	}
+	return client, nil
+}
+
+// targetDialer returns a dialer that applies the address overrides, address
+// family and proxy in opts, or nil to connect directly
+func targetDialer(opts Options) (health.DialFunc, error) {
+	var dial health.DialFunc
+	if opts.Proxy != "" {
+		var err error
+		dial, err = health.ProxyDialer(opts.Proxy, strings.Join(opts.NoProxy, ","))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.IPFamily == "4" || opts.IPFamily == "6" {
+		dial = health.FamilyDialer(opts.IPFamily, dial)
+	}
+
+	overrides, err := addressOverrides(opts)
+	if err != nil || len(overrides) == 0 {
+		return dial, err
+	}
+	return health.OverrideDialer(overrides, dial), nil
+}
+
+// addressOverrides parses opts.Resolve and opts.ConnectTo. Resolve entries
+// take precedence, as in curl.
+func addressOverrides(opts Options) ([]health.AddressOverride, error) {
+	var overrides []health.AddressOverride
+	for _, r := range opts.Resolve {
+		o, err := health.ParseResolve(r)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	for _, c := range opts.ConnectTo {
+		o, err := health.ParseConnectTo(c)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, nil
+}
+
+// httpProxy selects the proxy for HTTP requests: opts.Proxy when set,
+// otherwise HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment
+func httpProxy(opts Options) (func(*http.Request) (*url.URL, error), error) {
+	if opts.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	if _, err := health.ProxyDialer(opts.Proxy, ""); err != nil {
+		return nil, err
+	}
+
+	selectProxy := (&httpproxy.Config{
+		HTTPProxy:  opts.Proxy,
+		HTTPSProxy: opts.Proxy,
+		NoProxy:    strings.Join(opts.NoProxy, ","),
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return selectProxy(req.URL)
+	}, nil
+}
+
+func createClient(opts Options) (*http.Client, error) {
+	tlsOpts, err := tlsClientOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Without a ServerName override the transport uses each request's host
+	tlsConfig, err := tlsOpts.Config("")
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := httpProxy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := addressOverrides(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: opts.Insecure,
-		},
-		DisableKeepAlives:     true,
+		Proxy:                 proxy,
+		TLSClientConfig:       tlsConfig,
+		Protocols:             httpProtocols(opts.HTTPVersion),
+		DisableKeepAlives:     !opts.KeepAlive,
 		MaxIdleConns:          100,
 		MaxIdleConnsPerHost:   10,
-		IdleConnTimeout:       90 * time.Second,
+		IdleConnTimeout:       clientIdleTimeout,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 
+	var dial health.DialFunc
+	if opts.Proxy != "" && len(overrides) > 0 {
+		// Overrides rewrite the destination tunnelled through the proxy, as
+		// for tcp:// and ssl:// targets, rather than the proxy's address.
+		// Every request then goes through a CONNECT or SOCKS tunnel.
+		transport.Proxy = nil
+		if dial, err = targetDialer(opts); err != nil {
+			return nil, err
+		}
+	} else {
+		if opts.IPFamily == "4" || opts.IPFamily == "6" {
+			dial = health.FamilyDialer(opts.IPFamily, nil)
+		}
+		if len(overrides) > 0 {
+			dial = health.OverrideDialer(overrides, dial)
+		}
+	}
+	if dial != nil {
+		transport.DialContext = dial
+	}
+
 	client := &http.Client{
 		Timeout:   opts.Timeout,
 		Transport: transport,
 	}
 
+	if opts.HTTPVersion == "3" {
+		if opts.Proxy != "" {
+			return nil, fmt.Errorf("HTTP/3 cannot go through a proxy")
+		}
+		family := opts.IPFamily
+		if family != "4" && family != "6" {
+			family = ""
+		}
+		client.Transport = http3Transport(tlsConfig, overrides, family)
+	}
+
 	if !opts.FollowRedirects {
 		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}
 	}
 
-	return client
+	return client, nil
+}
+
+// clientIdleTimeout is how long idle connections are kept, and how long a
+// pooled client may go unused before it is dropped from the pool
+const clientIdleTimeout = 90 * time.Second
+
+// pooledClient is a client kept for Options.KeepAlive
+type pooledClient struct {
+	client   *http.Client
+	lastUsed time.Time
+}
+
+// clients keeps one HTTP client per target and option set for
+// Options.KeepAlive, so its idle connections carry over between checks
+var clients = struct {
+	sync.Mutex
+	entries map[string]*pooledClient
+}{entries: make(map[string]*pooledClient)}
+
+// targetClient returns the pooled client for target when opts.KeepAlive is
+// set, and a fresh one otherwise. Clients that have not been used for
+// clientIdleTimeout are evicted, since they no longer hold a connection worth
+// reusing; this keeps the pool bounded when targets are removed or, with
+// Options.AllIPs, addresses rotate out of DNS.
+func targetClient(target string, opts Options) (*http.Client, error) {
+	if !opts.KeepAlive {
+		return createClient(opts)
+	}
+
+	key := fmt.Sprintf("%s|%+v", target, opts)
+	now := time.Now()
+	clients.Lock()
+	defer clients.Unlock()
+	for k, p := range clients.entries {
+		if k != key && now.Sub(p.lastUsed) > clientIdleTimeout {
+			p.client.CloseIdleConnections()
+			delete(clients.entries, k)
+		}
+	}
+	if p, ok := clients.entries[key]; ok {
+		p.lastUsed = now
+		return p.client, nil
+	}
+	client, err := createClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	clients.entries[key] = &pooledClient{client: client, lastUsed: now}
+	return client, nil
 }
 
 func doHTTPCheck(url string, opts Options, client *http.Client) Result {
 	result := Result{URL: url}
+
+	// Fetching a token isn't part of the target's response time
+	var authHeader string
+	if opts.Auth != nil {
+		authClient, err := tokenClient(opts)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		value, err := authorization(opts.Auth, authClient)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		authHeader = value
+	}
+
 	start := time.Now()
 
 	var body io.Reader
@@ -239,27 +855,61 @@ func doHTTPCheck(url string, opts Options, client *http.Client) Result {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
 	for key, value := range opts.Headers {
 		req.Header.Set(key, value)
 	}
 
-	resp, err := client.Do(req)
+	// Note whether a warm connection was used
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.Reused = info.Reused
+		},
+	}))
+
+	// Record and check redirects for this request only
+	chain := &redirectChain{opts: opts, hopStart: start}
+	tracked := *client
+	tracked.CheckRedirect = chain.checkRedirect
+
+	resp, err := tracked.Do(req)
 	result.Duration = time.Since(start)
 
 	if err != nil {
+		result.Redirects = chain.hops
 		result.Error = fmt.Errorf("request failed: %w", err)
+		if chain.err != nil {
+			result.Error = chain.err
+		}
 		return result
 	}
 	defer resp.Body.Close()
+	chain.finish(resp)
+	result.Redirects = chain.hops
 
 	result.StatusCode = resp.StatusCode
 	result.Status = resp.Status
 	result.Headers = resp.Header
+	result.Protocol = resp.Proto
+
+	if resp.StatusCode == http.StatusUnauthorized && opts.Auth != nil && strings.EqualFold(opts.Auth.Type, "oauth2") {
+		invalidateToken(opts.Auth)
+	}
 
 	bodyBytes, _ := io.ReadAll(resp.Body)
 	result.Size = int64(len(bodyBytes))
 
 	result.Success = isSuccessCode(resp.StatusCode, opts.ExpectedCodes)
+	if err := checkHTTPVersion(resp, opts.HTTPVersion); err != nil {
+		result.Success = false
+		result.Error = err
+	} else if err := checkFinalURL(resp, opts); err != nil {
+		result.Success = false
+		result.Error = err
+	}
 
 	return result
 }
@@ -0,0 +1,56 @@
+package checker
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTargetClientEvictsUnused(t *testing.T) {
+	clients.Lock()
+	clients.entries = map[string]*pooledClient{
+		"stale": {client: &http.Client{}, lastUsed: time.Now().Add(-2 * clientIdleTimeout)},
+		"fresh": {client: &http.Client{}, lastUsed: time.Now()},
+	}
+	clients.Unlock()
+
+	opts := Options{KeepAlive: true, Timeout: time.Second}
+	first, err := targetClient("https://example.com", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := targetClient("https://example.com", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("client was not reused")
+	}
+
+	clients.Lock()
+	defer clients.Unlock()
+	if _, ok := clients.entries["stale"]; ok {
+		t.Error("stale client was not evicted")
+	}
+	if _, ok := clients.entries["fresh"]; !ok {
+		t.Error("fresh client was evicted")
+	}
+	if len(clients.entries) != 2 {
+		t.Errorf("pool has %d clients, want 2", len(clients.entries))
+	}
+}
+
+func TestTargetClientWithoutKeepAlive(t *testing.T) {
+	opts := Options{Timeout: time.Second}
+	first, err := targetClient("https://example.com", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := targetClient("https://example.com", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("client was pooled without KeepAlive")
+	}
+}
@@ -0,0 +1,74 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/TheRemyyy/gopunch/internal/health"
+)
+
+// parseHTTPVersion normalizes an HTTP version setting to "", "1.1", "2" or
+// "3"
+func parseHTTPVersion(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return "", nil
+	case "1", "1.1", "h1", "http/1.1":
+		return "1.1", nil
+	case "2", "h2", "http/2":
+		return "2", nil
+	case "3", "h3", "http/3":
+		return "3", nil
+	}
+	return "", fmt.Errorf("unknown HTTP version %q, want auto, 1.1, 2 or 3", s)
+}
+
+// httpProtocols returns what a TCP transport may speak for version: HTTP/1.1
+// or HTTP/2 negotiated with ALPN in auto mode, or exactly one of them.
+// Forcing HTTP/2 also sends cleartext HTTP/2 (h2c) to http:// targets.
+func httpProtocols(version string) *http.Protocols {
+	p := new(http.Protocols)
+	switch version {
+	case "1.1":
+		p.SetHTTP1(true)
+	case "2":
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+	default:
+		p.SetHTTP1(true)
+		p.SetHTTP2(true)
+	}
+	return p
+}
+
+// http3Transport sends requests over QUIC. Each connection gets its own UDP
+// socket, closed along with it, and honours the address overrides and
+// family the TCP dialers use.
+func http3Transport(tlsConfig *tls.Config, overrides []health.AddressOverride, family string) *http3.Transport {
+	return &http3.Transport{
+		TLSClientConfig: tlsConfig,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+			udpAddr, err := net.ResolveUDPAddr("udp"+family, health.RewriteAddress(overrides, addr))
+			if err != nil {
+				return nil, err
+			}
+			return quic.DialAddrEarly(ctx, udpAddr.String(), tlsCfg, cfg)
+		},
+	}
+}
+
+// checkHTTPVersion fails a response that didn't use the forced version
+func checkHTTPVersion(resp *http.Response, version string) error {
+	if version == "" || version == strconv.Itoa(resp.ProtoMajor) || (version == "1.1" && resp.ProtoMajor == 1) {
+		return nil
+	}
+	return fmt.Errorf("negotiated %s, want HTTP/%s", resp.Proto, version)
+}
@@ -0,0 +1,128 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Hop is one response in a redirect chain
+type Hop struct {
+	URL        string
+	StatusCode int
+	Duration   time.Duration // Time to this response's headers
+}
+
+// defaultMaxRedirects limits redirect chains when Options.MaxRedirects is
+// unset, as net/http does
+const defaultMaxRedirects = 10
+
+// redirectChain records the hops of one request and enforces the redirect
+// assertions in opts while they are followed
+type redirectChain struct {
+	opts     Options
+	hops     []Hop
+	hopStart time.Time
+	err      error // Why the chain was stopped, if an assertion failed
+}
+
+// checkRedirect is an http.Client CheckRedirect function
+func (c *redirectChain) checkRedirect(req *http.Request, via []*http.Request) error {
+	c.err = c.follow(req, via)
+	return c.err
+}
+
+func (c *redirectChain) follow(req *http.Request, via []*http.Request) error {
+	now := time.Now()
+	c.hops = append(c.hops, Hop{
+		URL:        via[len(via)-1].URL.String(),
+		StatusCode: req.Response.StatusCode,
+		Duration:   now.Sub(c.hopStart),
+	})
+	c.hopStart = now
+
+	if !c.opts.FollowRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	for _, v := range via {
+		if v.URL.String() == req.URL.String() {
+			return fmt.Errorf("redirect loop: %s", strings.Join(c.urls(req.URL.String()), " → "))
+		}
+	}
+	if c.opts.RequireHTTPS && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to insecure %s", req.URL)
+	}
+
+	limit := c.opts.MaxRedirects
+	if limit <= 0 {
+		limit = defaultMaxRedirects
+	}
+	if len(via) > limit {
+		return fmt.Errorf("more than %d redirects", limit)
+	}
+	return nil
+}
+
+// finish records the final response, if there were redirects before it
+func (c *redirectChain) finish(resp *http.Response) {
+	if len(c.hops) == 0 || !c.opts.FollowRedirects {
+		return
+	}
+	c.hops = append(c.hops, Hop{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Duration:   time.Since(c.hopStart),
+	})
+}
+
+func (c *redirectChain) urls(next string) []string {
+	urls := make([]string, 0, len(c.hops)+1)
+	for _, h := range c.hops {
+		urls = append(urls, h.URL)
+	}
+	return append(urls, next)
+}
+
+// checkFinalURL applies the https and final URL assertions to where the
+// chain ended: the last URL requested, or the target of a redirect that
+// wasn't followed
+func checkFinalURL(resp *http.Response, opts Options) error {
+	if !opts.RequireHTTPS && opts.FinalURL == "" {
+		return nil
+	}
+
+	final := resp.Request.URL
+	if !opts.FollowRedirects && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if loc, err := resp.Location(); err == nil {
+			final = loc
+		}
+	}
+
+	if opts.RequireHTTPS && final.Scheme != "https" {
+		return fmt.Errorf("not redirected to https, ended at %s", final)
+	}
+	if opts.FinalURL != "" {
+		want, err := url.Parse(opts.FinalURL)
+		if err != nil {
+			return fmt.Errorf("invalid final URL: %w", err)
+		}
+		if normalizeURL(final) != normalizeURL(want) {
+			return fmt.Errorf("ended at %s, want %s", final, opts.FinalURL)
+		}
+	}
+	return nil
+}
+
+// normalizeURL treats an empty path as "/" so that https://example.com and
+// https://example.com/ compare equal
+func normalizeURL(u *url.URL) string {
+	n := *u
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	n.Host = strings.ToLower(n.Host)
+	return n.String()
+}
@@ -0,0 +1,114 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirectServer serves /chain/N, which redirects N times before answering,
+// a loop between /loop/a and /loop/b, and /to?url=X, which redirects to X
+func redirectServer() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chain/{n}", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.PathValue("n"))
+		if n > 0 {
+			http.Redirect(w, r, "/chain/"+strconv.Itoa(n-1), http.StatusFound)
+		}
+	})
+	mux.HandleFunc("/loop/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop/b", http.StatusFound)
+	})
+	mux.HandleFunc("/loop/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop/a", http.StatusFound)
+	})
+	mux.HandleFunc("/to", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("url"), http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	return mux
+}
+
+func TestRedirects(t *testing.T) {
+	plain := httptest.NewServer(redirectServer())
+	defer plain.Close()
+	secure := httptest.NewTLSServer(redirectServer())
+	defer secure.Close()
+
+	to := func(base, target string) string {
+		return base + "/to?url=" + url.QueryEscape(target)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		opts     Options
+		noFollow bool
+		wantErr  string // Substring of the error, or "" when the check passes
+		wantHops int    // Only the redirect itself when not followed
+	}{
+		{name: "no redirect", target: plain.URL + "/chain/0"},
+		{name: "chain", target: plain.URL + "/chain/3", wantHops: 4},
+		{name: "loop", target: plain.URL + "/loop/a", wantErr: "redirect loop: " + plain.URL + "/loop/a → " + plain.URL + "/loop/b → " + plain.URL + "/loop/a"},
+		{name: "at max redirects", target: plain.URL + "/chain/2", opts: Options{MaxRedirects: 2}, wantHops: 3},
+		{name: "over max redirects", target: plain.URL + "/chain/3", opts: Options{MaxRedirects: 2}, wantErr: "more than 2 redirects"},
+		{name: "over default max redirects", target: plain.URL + "/chain/11", wantErr: "more than 10 redirects"},
+		{name: "require https", target: secure.URL + "/chain/2", opts: Options{RequireHTTPS: true}, wantHops: 3},
+		{name: "https to http", target: to(secure.URL, plain.URL+"/"), opts: Options{RequireHTTPS: true}, wantErr: "redirect to insecure " + plain.URL + "/"},
+		{name: "http to https", target: to(plain.URL, secure.URL+"/"), opts: Options{RequireHTTPS: true}, wantHops: 2},
+		{name: "never leaves http", target: plain.URL + "/chain/0", opts: Options{RequireHTTPS: true}, wantErr: "not redirected to https"},
+		{name: "final url", target: plain.URL + "/chain/1", opts: Options{FinalURL: plain.URL + "/chain/0"}, wantHops: 2},
+		{name: "wrong final url", target: plain.URL + "/chain/2", opts: Options{FinalURL: plain.URL + "/chain/1"}, wantErr: "ended at " + plain.URL + "/chain/0"},
+		{name: "final url without trailing slash", target: to(plain.URL, plain.URL+"/"), opts: Options{FinalURL: strings.ToUpper(plain.URL[:7]) + plain.URL[7:]}, wantHops: 2},
+		{name: "unfollowed redirect to https", target: to(plain.URL, "https://example.com/"), noFollow: true, opts: Options{RequireHTTPS: true}, wantHops: 1},
+		{name: "unfollowed redirect to http", target: to(plain.URL, "http://example.com/"), noFollow: true, opts: Options{RequireHTTPS: true}, wantErr: "ended at http://example.com/"},
+		{name: "unfollowed final url", target: to(plain.URL, "https://example.com"), noFollow: true, opts: Options{FinalURL: "https://example.com/"}, wantHops: 1},
+		{name: "unfollowed loop", target: plain.URL + "/loop/a", noFollow: true, wantHops: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.FollowRedirects = !tt.noFollow
+			opts.Insecure = true
+			opts.Timeout = 5 * time.Second
+
+			res := checkTarget(tt.target, opts)
+			if tt.wantErr == "" {
+				if !res.Success || res.Error != nil {
+					t.Fatalf("check failed: %v", res.Error)
+				}
+			} else if res.Error == nil || !strings.Contains(res.Error.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", res.Error, tt.wantErr)
+			}
+			if tt.wantErr == "" && len(res.Redirects) != tt.wantHops {
+				t.Errorf("recorded %d hops, want %d: %+v", len(res.Redirects), tt.wantHops, res.Redirects)
+			}
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"https://example.com", "https://example.com/", true},
+		{"https://Example.COM/", "https://example.com/", true},
+		{"https://example.com/path", "https://example.com/path/", false},
+		{"https://example.com/Path", "https://example.com/path", false},
+		{"https://example.com/?q=1", "https://example.com/", false},
+		{"http://example.com/", "https://example.com/", false},
+	}
+	for _, tt := range tests {
+		a, _ := url.Parse(tt.a)
+		b, _ := url.Parse(tt.b)
+		if got := normalizeURL(a) == normalizeURL(b); got != tt.equal {
+			t.Errorf("normalizeURL(%q) == normalizeURL(%q) is %v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+	}
+}
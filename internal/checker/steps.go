@@ -0,0 +1,281 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheRemyyy/gopunch/internal/health"
+)
+
+// Step is one request of a multi-step check. String fields may reference
+// variables extracted by earlier steps as {{name}}.
+type Step struct {
+	Name          string
+	Method        string
+	URL           string // Absolute, or relative to the target URL
+	Headers       map[string]string
+	Body          string
+	ExpectedCodes []int
+	Match         string            // Expected body (see health.ParseMatcher)
+	Extract       map[string]string // Variable name to "json:path", "header:Name" or "re:pattern"
+}
+
+// maxStepBody caps how much of each step's response is kept for matching
+// and extraction
+const maxStepBody = 1 << 20
+
+var stepVariable = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// checkSteps runs opts.Steps in order with a shared cookie jar and reports
+// the whole flow as one result with a timing per step. It stops at the
+// first step that fails.
+func checkSteps(target string, opts Options) Result {
+	result := Result{URL: target}
+
+	base, err := url.Parse(target)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	pooled, err := targetClient(target, opts)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if !opts.KeepAlive {
+		defer pooled.CloseIdleConnections()
+	}
+
+	// A fresh cookie jar per run, even when the connections are reused
+	client := *pooled
+	client.Jar, _ = cookiejar.New(nil)
+
+	vars := make(map[string]string)
+	for i, step := range opts.Steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+
+		res, err := runStep(&client, base, step, opts, vars)
+		result.Duration += res.Duration
+		result.Timings = append(result.Timings, Timing{Name: name, Duration: res.Duration})
+		result.StatusCode = res.StatusCode
+		result.Status = res.Status
+		result.Size = res.Size
+		result.Protocol = res.Protocol
+
+		if err != nil {
+			result.Error = fmt.Errorf("%s: %w", name, err)
+			result.Info = fmt.Sprintf("%d/%d steps", i, len(opts.Steps))
+			return result
+		}
+	}
+
+	result.Success = true
+	result.Info = fmt.Sprintf("%d/%d steps", len(opts.Steps), len(opts.Steps))
+	return result
+}
+
+// runStep sends one step's request, checks its assertions and stores any
+// extracted values in vars
+func runStep(client *http.Client, base *url.URL, step Step, opts Options, vars map[string]string) (Result, error) {
+	var result Result
+
+	ref, err := url.Parse(expandVars(step.URL, vars))
+	if err != nil {
+		return result, err
+	}
+	target := base.ResolveReference(ref)
+
+	var authHeader string
+	if opts.Auth != nil {
+		// Not the step client, which carries the flow's cookies and the
+		// target's TLS and address settings
+		authClient, err := tokenClient(opts)
+		if err != nil {
+			return result, err
+		}
+		if authHeader, err = authorization(opts.Auth, authClient); err != nil {
+			return result, err
+		}
+	}
+
+	method := strings.ToUpper(step.Method)
+	if method == "" {
+		method = "GET"
+	}
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(expandVars(step.Body, vars))
+	}
+
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return result, fmt.Errorf("request creation failed: %w", err)
+	}
+	req.Header.Set("User-Agent", "GoPunch/2.0")
+	if step.Body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+	for key, value := range opts.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range step.Headers {
+		req.Header.Set(key, expandVars(value, vars))
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Duration = time.Since(start)
+		return result, fmt.Errorf("request failed: %w", err)
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxStepBody))
+	resp.Body.Close()
+	result.Duration = time.Since(start)
+
+	result.StatusCode = resp.StatusCode
+	result.Status = resp.Status
+	result.Size = int64(len(respBody))
+	result.Protocol = resp.Proto
+
+	if err := checkHTTPVersion(resp, opts.HTTPVersion); err != nil {
+		return result, err
+	}
+
+	if !isSuccessCode(resp.StatusCode, step.ExpectedCodes) {
+		return result, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	expect, err := health.ParseMatcher(expandVars(step.Match, vars))
+	if err != nil {
+		return result, err
+	}
+	if expect != nil && !expect.Match(respBody) {
+		return result, fmt.Errorf("body does not match %q", expect)
+	}
+
+	for name, spec := range step.Extract {
+		value, err := extractValue(spec, resp.Header, respBody)
+		if err != nil {
+			return result, fmt.Errorf("extracting %s: %w", name, err)
+		}
+		vars[name] = value
+	}
+
+	return result, nil
+}
+
+// expandVars replaces {{name}} with the variable's value and {{env.NAME}}
+// with an environment variable. Unknown names are left in place.
+func expandVars(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return stepVariable.ReplaceAllStringFunc(s, func(m string) string {
+		name := stepVariable.FindStringSubmatch(m)[1]
+		if env, ok := strings.CutPrefix(name, "env."); ok {
+			return os.Getenv(env)
+		}
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// extractValue pulls a value out of a response. Specs are "json:<path>"
+// (dot-separated, with numeric indexes for arrays), "header:<name>" or
+// "re:<pattern>" (the first capture group, or the whole match).
+func extractValue(spec string, header http.Header, body []byte) (string, error) {
+	switch {
+	case strings.HasPrefix(spec, "json:"):
+		// Numbers are kept as written so that large IDs survive
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var doc any
+		if err := decoder.Decode(&doc); err != nil {
+			return "", fmt.Errorf("body is not JSON: %w", err)
+		}
+		return jsonPath(doc, strings.TrimPrefix(spec, "json:"))
+	case strings.HasPrefix(spec, "header:"):
+		name := strings.TrimPrefix(spec, "header:")
+		value := header.Get(name)
+		if value == "" {
+			return "", fmt.Errorf("no %s header", name)
+		}
+		return value, nil
+	case strings.HasPrefix(spec, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(spec, "re:"))
+		if err != nil {
+			return "", err
+		}
+		m := re.FindSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("pattern did not match")
+		}
+		if len(m) > 1 {
+			return string(m[1]), nil
+		}
+		return string(m[0]), nil
+	}
+	return "", fmt.Errorf("unknown extract spec %q", spec)
+}
+
+// jsonPath walks a decoded JSON document and formats the value found
+func jsonPath(doc any, path string) (string, error) {
+	current := doc
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch node := current.(type) {
+			case map[string]any:
+				v, ok := node[key]
+				if !ok {
+					return "", fmt.Errorf("no field %q", key)
+				}
+				current = v
+			case []any:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(node) {
+					return "", fmt.Errorf("no index %q", key)
+				}
+				current = node[i]
+			default:
+				return "", fmt.Errorf("cannot descend into %q", key)
+			}
+		}
+	}
+
+	switch v := current.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", fmt.Errorf("value at %q is null", path)
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,129 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStepsTokenRequestHasNoCookies(t *testing.T) {
+	var tokenCookies []string
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenCookies = append(tokenCookies, r.Header.Values("Cookie")...)
+		fmt.Fprint(w, `{"access_token":"flow-token","expires_in":1}`)
+	}))
+	defer tokenSrv.Close()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer flow-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+	}))
+	defer target.Close()
+
+	// The token expires at once, so each step fetches its own. The first
+	// step sets a cookie for 127.0.0.1, which the token endpoint shares.
+	opts := Options{
+		Timeout: 5 * time.Second,
+		Auth:    oauth2Auth(tokenSrv.URL),
+		Steps:   []Step{{Name: "login", URL: "/login"}, {Name: "home", URL: "/"}},
+	}
+	if res := checkTarget(target.URL, opts); !res.Success {
+		t.Fatalf("flow failed: %v", res.Error)
+	}
+
+	if len(tokenCookies) > 0 {
+		t.Errorf("token endpoint received cookies %q", tokenCookies)
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	body := `{
+		"data": {
+			"token": "abc",
+			"items": [{"id": 7}, {"id": 12345678901234567890}],
+			"price": 1.5,
+			"active": true,
+			"deleted": null,
+			"tags": ["a", "b"]
+		}
+	}`
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "data.token", want: "abc"},
+		{path: "data.items.0.id", want: "7"},
+		{path: "data.items.1.id", want: "12345678901234567890"},
+		{path: "data.price", want: "1.5"},
+		{path: "data.active", want: "true"},
+		{path: "data.tags", want: `["a","b"]`},
+		{path: "data.items.0", want: `{"id":7}`},
+		{path: "data.deleted", wantErr: true},
+		{path: "data.missing", wantErr: true},
+		{path: "data.items.2", wantErr: true},
+		{path: "data.items.-1", wantErr: true},
+		{path: "data.items.first", wantErr: true},
+		{path: "data.token.length", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := extractValue("json:"+tt.path, nil, []byte(body))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("json:%s = %q, %v, want %q, error %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExtractValue(t *testing.T) {
+	header := http.Header{"X-Csrf-Token": {"tok123"}}
+	body := []byte(`<a data-id="42">first</a> <a data-id="43">second</a>`)
+
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "header:X-CSRF-Token", want: "tok123"},
+		{spec: "header:x-csrf-token", want: "tok123"},
+		{spec: "header:X-Missing", wantErr: true},
+		{spec: `re:data-id="(\d+)"`, want: "42"},
+		{spec: `re:data-id="\d+"`, want: `data-id="42"`},
+		{spec: "re:nothing here", wantErr: true},
+		{spec: "re:(", wantErr: true},
+		{spec: "json:data", wantErr: true},
+		{spec: "xpath://a", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := extractValue(tt.spec, header, body)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("extractValue(%q) = %q, %v, want %q, error %v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandVars(t *testing.T) {
+	t.Setenv("GOPUNCH_TEST_PASSWORD", "hunter2")
+	vars := map[string]string{"token": "abc", "first_id": "42"}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"/api/items/{{first_id}}", "/api/items/42"},
+		{"Bearer {{ token }}", "Bearer abc"},
+		{`{"password": "{{env.GOPUNCH_TEST_PASSWORD}}"}`, `{"password": "hunter2"}`},
+		{"{{env.GOPUNCH_TEST_UNSET}}", ""},
+		{"{{unknown}} and {{token}}", "{{unknown}} and abc"},
+		{"{{ not a var }}", "{{ not a var }}"},
+		{"no variables", "no variables"},
+	}
+	for _, tt := range tests {
+		if got := expandVars(tt.in, vars); got != tt.want {
+			t.Errorf("expandVars(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSOptions configures a record lookup
+type DNSOptions struct {
+	Type    string   // A, AAAA, CNAME, MX, TXT, NS, SRV or CAA
+	Server  string   // Nameserver to query (host or host:port); system resolver if empty
+	Expect  []string // Values that must appear in the answers
+	Timeout time.Duration
+}
+
+// CheckDNSRecord looks up records of the given type and verifies that every
+// expected value is present in the answers
+func CheckDNSRecord(host string, opts DNSOptions) DNSResult {
+	result := DNSResult{Host: host, Type: strings.ToUpper(opts.Type), Server: opts.Server}
+	if result.Type == "" {
+		result.Type = "A"
+	}
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	answers, err := lookupRecords(ctx, host, result.Type, opts.Server)
+	result.Duration = time.Since(start)
+	result.Answers = answers
+
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if len(answers) == 0 {
+		result.Error = fmt.Errorf("no %s records found", result.Type)
+		return result
+	}
+
+	for _, exp := range opts.Expect {
+		if !containsAnswer(answers, result.Type, exp) {
+			result.Missing = append(result.Missing, exp)
+		}
+	}
+	if len(result.Missing) > 0 {
+		result.Error = fmt.Errorf("%s answers missing %s", result.Type, strings.Join(result.Missing, ", "))
+		return result
+	}
+
+	if result.Type == "A" || result.Type == "AAAA" {
+		result.IPs = answers
+	}
+	result.Resolved = true
+	return result
+}
+
+// DNSComparison holds the answers from several nameservers for one name
+type DNSComparison struct {
+	Host       string
+	Type       string
+	Results    []DNSResult // One per server, in the order given
+	Groups     [][]string  // Servers grouped by identical answer sets
+	Consistent bool        // Every server resolved and returned the same answers
+	Duration   time.Duration
+}
+
+// CompareDNS queries every server in parallel with the same lookup and
+// reports whether their answers agree. Answer order, case and trailing
+// dots are ignored.
+func CompareDNS(host string, servers []string, opts DNSOptions) DNSComparison {
+	cmp := DNSComparison{Host: host, Type: strings.ToUpper(opts.Type)}
+	if cmp.Type == "" {
+		cmp.Type = "A"
+	}
+	start := time.Now()
+
+	cmp.Results = make([]DNSResult, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			o := opts
+			o.Server = server
+			cmp.Results[i] = CheckDNSRecord(host, o)
+		}(i, server)
+	}
+	wg.Wait()
+	cmp.Duration = time.Since(start)
+
+	index := make(map[string]int)
+	resolved := true
+	for i, res := range cmp.Results {
+		key := answerKey(res)
+		if !res.Resolved {
+			resolved = false
+		}
+		g, ok := index[key]
+		if !ok {
+			g = len(cmp.Groups)
+			index[key] = g
+			cmp.Groups = append(cmp.Groups, nil)
+		}
+		cmp.Groups[g] = append(cmp.Groups[g], servers[i])
+	}
+	cmp.Consistent = resolved && len(cmp.Groups) == 1
+	return cmp
+}
+
+// answerKey normalizes a result's answers so equal sets compare equal.
+// Failed lookups share one key.
+func answerKey(res DNSResult) string {
+	if len(res.Answers) == 0 {
+		return ""
+	}
+	normalized := make([]string, len(res.Answers))
+	for i, a := range res.Answers {
+		normalized[i] = strings.ToLower(trimDot(a))
+	}
+	sort.Strings(normalized)
+	return strings.Join(normalized, "\n")
+}
+
+// newResolver returns the system resolver, or one that sends every query to
+// server when it is set
+func newResolver(server string) *net.Resolver {
+	if server == "" {
+		return &net.Resolver{}
+	}
+	address := nameserverAddress(server)
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// nameserverAddress adds the default DNS port when server has none
+func nameserverAddress(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+}
+
+func lookupRecords(ctx context.Context, host, recordType, server string) ([]string, error) {
+	resolver := newResolver(server)
+	var answers []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, trimDot(cname))
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, trimDot(mx.Host)))
+		}
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		answers = txts
+	case "NS":
+		nss, err := resolver.LookupNS(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			answers = append(answers, trimDot(ns.Host))
+		}
+	case "SRV":
+		_, srvs, err := resolver.LookupSRV(ctx, "", "", host)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range srvs {
+			answers = append(answers, fmt.Sprintf("%d %d %d %s", srv.Priority, srv.Weight, srv.Port, trimDot(srv.Target)))
+		}
+	case "CAA":
+		return lookupCAA(ctx, host, server)
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", recordType)
+	}
+
+	return answers, nil
+}
+
+// typeCAA is the CAA resource record type (RFC 8659), which dnsmessage
+// does not define
+const typeCAA dnsmessage.Type = 257
+
+// lookupCAA queries CAA records directly, since net.Resolver has no CAA
+// support. Without an explicit server the first system nameserver is used.
+func lookupCAA(ctx context.Context, host, server string) ([]string, error) {
+	if server == "" {
+		server = systemNameserver()
+		if server == "" {
+			return nil, fmt.Errorf("CAA lookups need a nameserver")
+		}
+	}
+
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		return nil, err
+	}
+
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Intn(1 << 16)), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: typeCAA, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", nameserverAddress(server))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf[:n]); err != nil {
+		return nil, fmt.Errorf("invalid DNS response: %w", err)
+	}
+	if resp.Header.ID != query.Header.ID {
+		return nil, fmt.Errorf("mismatched DNS response")
+	}
+	if resp.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("lookup %s: %s", host, resp.Header.RCode)
+	}
+
+	var answers []string
+	for _, rr := range resp.Answers {
+		if rr.Header.Type != typeCAA {
+			continue
+		}
+		if u, ok := rr.Body.(*dnsmessage.UnknownResource); ok {
+			if caa, ok := parseCAA(u.Data); ok {
+				answers = append(answers, caa)
+			}
+		}
+	}
+	return answers, nil
+}
+
+// parseCAA formats CAA record data as `flags tag "value"`
+func parseCAA(data []byte) (string, bool) {
+	if len(data) < 2 {
+		return "", false
+	}
+	flags, tagLen := data[0], int(data[1])
+	if len(data) < 2+tagLen {
+		return "", false
+	}
+	tag := string(data[2 : 2+tagLen])
+	value := string(data[2+tagLen:])
+	return fmt.Sprintf("%d %s %q", flags, tag, value), true
+}
+
+// systemNameserver returns the first nameserver from /etc/resolv.conf
+func systemNameserver() string {
+	data, err := os.ReadFile("/etc/resolv.conf")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// containsAnswer reports whether any answer matches the expected value (see
+// answerMatches)
+func containsAnswer(answers []string, recordType, expected string) bool {
+	for _, a := range answers {
+		if answerMatches(a, recordType, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// answerMatches compares one answer with an expected value, ignoring case
+// and trailing dots. Addresses compare as IPs, MX and SRV answers also match
+// on their target host alone and CAA answers on their value alone.
+func answerMatches(answer, recordType, expected string) bool {
+	if normalizeAnswer(answer) == normalizeAnswer(expected) {
+		return true
+	}
+	switch recordType {
+	case "A", "AAAA":
+		a, e := net.ParseIP(answer), net.ParseIP(strings.TrimSpace(expected))
+		return a != nil && e != nil && a.Equal(e)
+	case "MX", "SRV":
+		fields := strings.Fields(answer)
+		return len(fields) > 1 && normalizeAnswer(fields[len(fields)-1]) == normalizeAnswer(expected)
+	case "CAA":
+		parts := strings.SplitN(answer, " ", 3)
+		if len(parts) == 3 {
+			value, err := strconv.Unquote(parts[2])
+			return err == nil && strings.EqualFold(value, strings.TrimSpace(expected))
+		}
+	}
+	return false
+}
+
+func normalizeAnswer(s string) string {
+	return strings.ToLower(trimDot(strings.TrimSpace(s)))
+}
+
+func trimDot(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
+
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
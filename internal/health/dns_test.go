@@ -0,0 +1,63 @@
+package health
+
+import "testing"
+
+func TestContainsAnswer(t *testing.T) {
+	tests := []struct {
+		name       string
+		answers    []string
+		recordType string
+		expected   string
+		want       bool
+	}{
+		{"A exact", []string{"10.0.0.1"}, "A", "10.0.0.1", true},
+		{"A prefix of another address", []string{"10.0.0.10"}, "A", "10.0.0.1", false},
+		{"A any answer", []string{"10.0.0.2", "10.0.0.1"}, "A", "10.0.0.1", true},
+		{"AAAA expanded", []string{"2001:db8::1"}, "AAAA", "2001:0db8:0000::0001", true},
+		{"AAAA different", []string{"2001:db8::1"}, "AAAA", "2001:db8::10", false},
+		{"CNAME case and trailing dot", []string{"lb.example.com"}, "CNAME", "LB.Example.com.", true},
+		{"CNAME suffix", []string{"evil-lb.example.com"}, "CNAME", "lb.example.com", false},
+		{"MX full answer", []string{"10 mail.example.com"}, "MX", "10 mail.example.com", true},
+		{"MX host only", []string{"10 mail.example.com"}, "MX", "mail.example.com.", true},
+		{"MX host suffix", []string{"10 evilmail.example.com"}, "MX", "mail.example.com", false},
+		{"MX preference only", []string{"10 mail.example.com"}, "MX", "10", false},
+		{"SRV host only", []string{"10 5 5060 sip.example.com"}, "SRV", "sip.example.com", true},
+		{"SRV port only", []string{"10 5 5060 sip.example.com"}, "SRV", "5060", false},
+		{"TXT exact", []string{"google-site-verification=abc123"}, "TXT", "google-site-verification=abc123", true},
+		{"TXT prefix", []string{"google-site-verification=abc123"}, "TXT", "google-site-verification", false},
+		{"CAA full answer", []string{`0 issue "letsencrypt.org"`}, "CAA", `0 issue "letsencrypt.org"`, true},
+		{"CAA value only", []string{`0 issue "letsencrypt.org"`}, "CAA", "LetsEncrypt.org", true},
+		{"CAA tag only", []string{`0 issue "letsencrypt.org"`}, "CAA", "issue", false},
+		{"no answers", nil, "A", "10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsAnswer(tt.answers, tt.recordType, tt.expected); got != tt.want {
+				t.Errorf("containsAnswer(%q, %s, %q) = %v, want %v", tt.answers, tt.recordType, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCAA(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		ok   bool
+	}{
+		{"issue", append([]byte{0, 5}, "issueletsencrypt.org"...), `0 issue "letsencrypt.org"`, true},
+		{"critical iodef", append([]byte{128, 5}, "iodefmailto:a@example.com"...), `128 iodef "mailto:a@example.com"`, true},
+		{"empty value", append([]byte{0, 9}, "issuewild"...), `0 issuewild ""`, true},
+		{"truncated tag", append([]byte{0, 9}, "iss"...), "", false},
+		{"too short", []byte{0}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCAA(tt.data)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseCAA() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
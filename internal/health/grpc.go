@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPCOptions configures a gRPC health check
+type GRPCOptions struct {
+	Timeout  time.Duration
+	TLS      bool              // Use TLS instead of plaintext
+	Insecure bool              // Skip TLS certificate verification
+	Metadata map[string]string // Sent as request metadata
+}
+
+// GRPCResult represents gRPC health check result
+type GRPCResult struct {
+	Address  string
+	Service  string
+	Status   string // SERVING, NOT_SERVING, UNKNOWN, SERVICE_UNKNOWN
+	Serving  bool
+	Duration time.Duration
+	Error    error
+}
+
+// CheckGRPC calls grpc.health.v1.Health/Check on the given address. An empty
+// service asks for the overall health of the server.
+func CheckGRPC(address, service string, opts GRPCOptions) GRPCResult {
+	result := GRPCResult{Address: address, Service: service}
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	creds := insecure.NewCredentials()
+	if opts.TLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: opts.Insecure})
+	}
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		return result
+	}
+	defer conn.Close()
+
+	if len(opts.Metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(opts.Metadata))
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	result.Duration = time.Since(start)
+
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Status = resp.GetStatus().String()
+	result.Serving = resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	return result
+}
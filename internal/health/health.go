@@ -3,28 +3,54 @@ package health
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"syscall"
 	"time"
 )
 
+// maxProbeResponse caps how much of a probe reply is read
+const maxProbeResponse = 64 * 1024
+
 // TCPResult represents TCP port check result
 type TCPResult struct {
-	Host     string
-	Port     int
-	Open     bool
-	Duration time.Duration
-	Error    error
+	Host        string
+	Port        int
+	Open        bool
+	Matched     bool   // Reply matched the expectation (probes only)
+	Response    []byte // Data read from the connection (probes only)
+	ConnectTime time.Duration
+	Duration    time.Duration
+	Error       error
+}
+
+// TCPProbe sends data after connecting and/or expects a reply
+type TCPProbe struct {
+	Send        []byte
+	Expect      *Matcher
+	ReadTimeout time.Duration // Defaults to the dial timeout
+	Dial        DialFunc      // Connects through a proxy; direct when nil
 }
 
 // CheckTCP checks if a TCP port is open
 func CheckTCP(host string, port int, timeout time.Duration) TCPResult {
+	return CheckTCPProbe(host, port, timeout, TCPProbe{})
+}
+
+// CheckTCPProbe checks that a TCP port is open and, when the probe has an
+// expectation, that the service answers with a matching reply. This catches
+// hung services that still accept connections, e.g. by reading an SMTP or
+// SSH banner.
+func CheckTCPProbe(host string, port int, timeout time.Duration, probe TCPProbe) TCPResult {
 	result := TCPResult{Host: host, Port: port}
 	start := time.Now()
 
-	address := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
-	result.Duration = time.Since(start)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := dial(probe.Dial, "tcp", address, timeout)
+	result.ConnectTime = time.Since(start)
+	result.Duration = result.ConnectTime
 
 	if err != nil {
 		result.Error = err
@@ -33,14 +59,162 @@ func CheckTCP(host string, port int, timeout time.Duration) TCPResult {
 	}
 	defer conn.Close()
 
+	if len(probe.Send) == 0 && probe.Expect == nil {
+		result.Open = true
+		return result
+	}
+
+	readTimeout := probe.ReadTimeout
+	if readTimeout == 0 {
+		readTimeout = timeout
+	}
+	conn.SetDeadline(time.Now().Add(readTimeout))
+
+	if len(probe.Send) > 0 {
+		if _, err := conn.Write(probe.Send); err != nil {
+			result.Error = fmt.Errorf("send failed: %w", err)
+			result.Duration = time.Since(start)
+			return result
+		}
+	}
+
+	if probe.Expect == nil {
+		result.Open = true
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	result.Response, result.Matched, err = readUntilMatch(conn, probe.Expect)
+	result.Duration = time.Since(start)
 	result.Open = true
+	if !result.Matched {
+		result.Error = unmatchedError(probe.Expect, result.Response, err)
+	}
 	return result
 }
 
+// readUntilMatch reads from conn until the data matches, the deadline
+// passes, the peer closes the connection or the size cap is reached
+func readUntilMatch(conn net.Conn, expect *Matcher) ([]byte, bool, error) {
+	var data []byte
+	buf := make([]byte, 4096)
+	for len(data) < maxProbeResponse {
+		n, err := conn.Read(buf)
+		data = append(data, buf[:n]...)
+		if expect.Match(data) {
+			return data, true, nil
+		}
+		if err != nil {
+			return data, false, err
+		}
+	}
+	return data, false, nil
+}
+
+// unmatchedError describes a reply that did not match the expectation
+func unmatchedError(expect *Matcher, response []byte, err error) error {
+	if len(response) == 0 {
+		if err != nil {
+			return fmt.Errorf("no response: %w", err)
+		}
+		return fmt.Errorf("no response")
+	}
+	snippet := string(response)
+	if len(snippet) > 64 {
+		snippet = snippet[:64] + "..."
+	}
+	return fmt.Errorf("response %q does not match %q", snippet, expect)
+}
+
+// UDPResult represents UDP probe result
+type UDPResult struct {
+	Host     string
+	Port     int
+	Up       bool
+	Replied  bool
+	Response []byte
+	Duration time.Duration
+	Error    error
+}
+
+// UDPProbe configures what a UDP check sends and expects
+type UDPProbe struct {
+	Send          []byte
+	Expect        *Matcher
+	ReadTimeout   time.Duration // Defaults to the dial timeout
+	AcceptNoReply bool          // Treat a read timeout as up (for fire-and-forget services)
+}
+
+// CheckUDP sends a datagram and waits for a reply. Because UDP is
+// connectionless, a closed port is only detected through the ICMP
+// port-unreachable message, which surfaces as a refused read.
+func CheckUDP(host string, port int, timeout time.Duration, probe UDPProbe) UDPResult {
+	result := UDPResult{Host: host, Port: port}
+	start := time.Now()
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		return result
+	}
+	defer conn.Close()
+
+	readTimeout := probe.ReadTimeout
+	if readTimeout == 0 {
+		readTimeout = timeout
+	}
+	conn.SetDeadline(time.Now().Add(readTimeout))
+
+	if _, err := conn.Write(probe.Send); err != nil {
+		result.Error = fmt.Errorf("send failed: %w", err)
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	buf := make([]byte, maxProbeResponse)
+	for {
+		n, err := conn.Read(buf)
+		result.Duration = time.Since(start)
+
+		if err != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				result.Error = fmt.Errorf("port unreachable")
+				return result
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if probe.AcceptNoReply && !result.Replied {
+					result.Up = true
+					return result
+				}
+			}
+			if probe.Expect == nil {
+				result.Error = fmt.Errorf("no reply: %w", err)
+			} else {
+				result.Error = unmatchedError(probe.Expect, result.Response, err)
+			}
+			return result
+		}
+
+		result.Replied = true
+		result.Response = append([]byte(nil), buf[:n]...)
+		if probe.Expect == nil || probe.Expect.Match(result.Response) {
+			result.Up = true
+			return result
+		}
+	}
+}
+
 // DNSResult represents DNS resolution result
 type DNSResult struct {
 	Host     string
+	Type     string // Record type, set by CheckDNSRecord
+	Server   string // Nameserver queried, empty for the system resolver
 	IPs      []string
+	Answers  []string
+	Missing  []string // Expected values not found in Answers
 	Duration time.Duration
 	Error    error
 	Resolved bool
@@ -76,51 +250,98 @@ type SSLResult struct {
 	Valid     bool
 	Issuer    string
 	Subject   string
+	Serial    string
 	NotBefore time.Time
 	NotAfter  time.Time
 	DaysLeft  int
 	Duration  time.Duration
 	Error     error
+
+	Version            string     // Negotiated protocol, e.g. "TLS 1.3"
+	CipherSuite        string     // Negotiated cipher suite
+	KeyType            string     // Leaf public key: RSA, ECDSA or Ed25519
+	KeyBits            int        // Leaf key size
+	SignatureAlgorithm string     // Leaf signature algorithm
+	DNSNames           []string   // Leaf subject alternative names
+	HostnameMatch      bool       // Host is covered by the leaf certificate
+	OCSPStapled        bool       // Server stapled an OCSP response
+	OCSPStatus         string     // good, revoked or unknown, when stapled
+	Chain              []CertInfo // Leaf first, then intermediates and root
+	ChainDaysLeft      int        // Days until the first certificate in the chain expires
+	Violations         []string   // TLS policy violations
 }
 
-// CheckSSL checks SSL certificate validity and expiry
+// SSLOptions configures a TLS inspection
+type SSLOptions struct {
+	Timeout  time.Duration
+	Policy   TLSPolicy
+	StartTLS string // Protocol to upgrade from plain text: smtp, imap, pop3, ftp, ldap or postgres
+	Client   TLSClientOptions
+	Dial     DialFunc // Connects through a proxy; direct when nil
+}
+
+// CheckSSL checks SSL certificate validity and expiry using the default
+// TLS policy
 func CheckSSL(host string, port int, timeout time.Duration) SSLResult {
+	return CheckSSLWithOptions(host, port, SSLOptions{Timeout: timeout, Policy: DefaultTLSPolicy()})
+}
+
+// CheckSSLWithOptions performs a TLS handshake, after a STARTTLS upgrade if
+// one is configured, inspects the certificate chain and connection, and
+// fails when the policy is violated
+func CheckSSLWithOptions(host string, port int, opts SSLOptions) SSLResult {
 	result := SSLResult{Host: host, Port: port}
 	start := time.Now()
 
 	if port == 0 {
 		port = 443
+		if p, ok := StartTLSPorts[opts.StartTLS]; ok {
+			port = p
+		}
 	}
 
-	address := fmt.Sprintf("%s:%d", host, port)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
 
-	dialer := &net.Dialer{Timeout: timeout}
-	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
-		InsecureSkipVerify: false,
-	})
-	result.Duration = time.Since(start)
+	cfg, err := opts.Client.Config(host)
+	if err != nil {
+		result.Error = err
+		return result
+	}
 
+	rawConn, err := dial(opts.Dial, "tcp", address, opts.Timeout)
 	if err != nil {
+		result.Duration = time.Since(start)
 		result.Error = err
-		result.Valid = false
 		return result
 	}
-	defer conn.Close()
+	defer rawConn.Close()
+	rawConn.SetDeadline(time.Now().Add(opts.Timeout))
+
+	if opts.StartTLS != "" {
+		if err := startTLS(rawConn, opts.StartTLS); err != nil {
+			result.Duration = time.Since(start)
+			result.Error = err
+			return result
+		}
+	}
 
-	certs := conn.ConnectionState().PeerCertificates
-	if len(certs) == 0 {
-		result.Error = fmt.Errorf("no certificates found")
+	// The chain is verified after the handshake so that it can still be
+	// inspected when verification fails
+	roots, serverName := cfg.RootCAs, cfg.ServerName
+	cfg.InsecureSkipVerify = true
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS10
+	}
+	conn := tls.Client(rawConn, cfg)
+	err = conn.Handshake()
+	result.Duration = time.Since(start)
+
+	if err != nil {
+		result.Error = err
 		result.Valid = false
 		return result
 	}
 
-	cert := certs[0]
-	result.Issuer = cert.Issuer.CommonName
-	result.Subject = cert.Subject.CommonName
-	result.NotBefore = cert.NotBefore
-	result.NotAfter = cert.NotAfter
-	result.DaysLeft = int(time.Until(cert.NotAfter).Hours() / 24)
-	result.Valid = time.Now().Before(cert.NotAfter) && time.Now().After(cert.NotBefore)
-
+	inspectTLS(&result, conn.ConnectionState(), serverName, roots, opts.Policy)
 	return result
 }
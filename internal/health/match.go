@@ -0,0 +1,77 @@
+package health
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matcher checks a response against an expectation. Specs are plain text
+// (substring match), "re:<pattern>" (regular expression) or "hex:<bytes>"
+// (byte sequence).
+type Matcher struct {
+	spec  string
+	re    *regexp.Regexp
+	bytes []byte
+}
+
+// ParseMatcher compiles an expectation spec. An empty spec returns nil.
+func ParseMatcher(spec string) (*Matcher, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	m := &Matcher{spec: spec}
+	switch {
+	case strings.HasPrefix(spec, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(spec, "re:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect pattern: %w", err)
+		}
+		m.re = re
+	case strings.HasPrefix(spec, "hex:"):
+		b, err := ParsePayload(spec)
+		if err != nil {
+			return nil, err
+		}
+		m.bytes = b
+	default:
+		m.bytes = []byte(spec)
+	}
+	return m, nil
+}
+
+// Match reports whether data satisfies the expectation
+func (m *Matcher) Match(data []byte) bool {
+	if m.re != nil {
+		return m.re.Match(data)
+	}
+	return bytes.Contains(data, m.bytes)
+}
+
+// String returns the original spec
+func (m *Matcher) String() string {
+	return m.spec
+}
+
+// ParsePayload decodes data to send. "hex:<bytes>" is decoded as hex (spaces
+// and colons are ignored); anything else is sent as text with Go escape
+// sequences such as \r\n interpreted.
+func ParsePayload(spec string) ([]byte, error) {
+	if strings.HasPrefix(spec, "hex:") {
+		clean := strings.NewReplacer(" ", "", ":", "").Replace(strings.TrimPrefix(spec, "hex:"))
+		b, err := hex.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex payload: %w", err)
+		}
+		return b, nil
+	}
+
+	if unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(spec, `"`, `\"`) + `"`); err == nil {
+		return []byte(unquoted), nil
+	}
+	return []byte(spec), nil
+}
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// AddressOverride sends connections for Host:Port to ToHost:ToPort instead,
+// like curl's --resolve and --connect-to. An empty Host or Port matches any;
+// an empty ToHost or ToPort keeps the original.
+type AddressOverride struct {
+	Host   string
+	Port   string
+	ToHost string
+	ToPort string
+}
+
+// ParseResolve parses curl's --resolve syntax, host:port:address. The port
+// may be "*" to match any.
+func ParseResolve(s string) (AddressOverride, error) {
+	parts := splitAddressSpec(s)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return AddressOverride{}, fmt.Errorf("invalid resolve %q, want host:port:address", s)
+	}
+	o := AddressOverride{Host: parts[0], Port: parts[1], ToHost: parts[2]}
+	if o.Port == "*" {
+		o.Port = ""
+	}
+	if net.ParseIP(o.ToHost) == nil {
+		return AddressOverride{}, fmt.Errorf("invalid resolve %q: %q is not an IP address", s, o.ToHost)
+	}
+	return o, nil
+}
+
+// ParseConnectTo parses curl's --connect-to syntax,
+// host:port:connect-host:connect-port, where any field may be empty
+func ParseConnectTo(s string) (AddressOverride, error) {
+	parts := splitAddressSpec(s)
+	if len(parts) != 4 {
+		return AddressOverride{}, fmt.Errorf("invalid connect-to %q, want host:port:connect-host:connect-port", s)
+	}
+	return AddressOverride{Host: parts[0], Port: parts[1], ToHost: parts[2], ToPort: parts[3]}, nil
+}
+
+// splitAddressSpec splits on colons outside square brackets and strips the
+// brackets from IPv6 addresses
+func splitAddressSpec(s string) []string {
+	var parts []string
+	var current strings.Builder
+	inBrackets := false
+	for _, r := range s {
+		switch {
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case r == ':' && !inBrackets:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(parts, current.String())
+}
+
+func (o AddressOverride) rewrite(host, port string) (string, bool) {
+	if o.Host != "" && !strings.EqualFold(o.Host, host) {
+		return "", false
+	}
+	if o.Port != "" && o.Port != port {
+		return "", false
+	}
+	if o.ToHost != "" {
+		host = o.ToHost
+	}
+	if o.ToPort != "" {
+		port = o.ToPort
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// OverrideDialer returns a DialFunc that applies the first matching
+// override to each address before dialing it with next (directly when nil).
+// Only the connection moves; callers still use the original host for the
+// Host header and SNI.
+func OverrideDialer(overrides []AddressOverride, next DialFunc) DialFunc {
+	if next == nil {
+		var d net.Dialer
+		next = d.DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return next(ctx, network, RewriteAddress(overrides, address))
+	}
+}
+
+// RewriteAddress applies the first override matching address (host:port),
+// for transports that don't dial through a DialFunc
+func RewriteAddress(overrides []AddressOverride, address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	for _, o := range overrides {
+		if rewritten, ok := o.rewrite(host, port); ok {
+			return rewritten
+		}
+	}
+	return address
+}
+
+// FamilyDialer returns a DialFunc that only connects over IPv4 (family "4")
+// or IPv6 ("6"), dialing with next (directly when nil). Through a proxy the
+// family only applies to destinations that bypass it.
+func FamilyDialer(family string, next DialFunc) DialFunc {
+	if next == nil {
+		var d net.Dialer
+		next = d.DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network == "tcp" || network == "udp" {
+			network += family
+		}
+		return next(ctx, network, address)
+	}
+}
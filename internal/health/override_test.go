@@ -0,0 +1,83 @@
+package health
+
+import "testing"
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AddressOverride
+		wantErr bool
+	}{
+		{in: "example.com:443:203.0.113.10", want: AddressOverride{Host: "example.com", Port: "443", ToHost: "203.0.113.10"}},
+		{in: "example.com:*:203.0.113.10", want: AddressOverride{Host: "example.com", ToHost: "203.0.113.10"}},
+		{in: "example.com:443:[2001:db8::1]", want: AddressOverride{Host: "example.com", Port: "443", ToHost: "2001:db8::1"}},
+		{in: "[2001:db8::5]:80:203.0.113.10", want: AddressOverride{Host: "2001:db8::5", Port: "80", ToHost: "203.0.113.10"}},
+		{in: "example.com:443:backend.internal", wantErr: true},
+		{in: "example.com:443", wantErr: true},
+		{in: ":443:203.0.113.10", wantErr: true},
+		{in: "example.com:443:", wantErr: true},
+		{in: "example.com:443:203.0.113.10:8443", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseResolve(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseResolve(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseResolve(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseConnectTo(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AddressOverride
+		wantErr bool
+	}{
+		{in: "api.example.com:443:canary.internal:8443", want: AddressOverride{Host: "api.example.com", Port: "443", ToHost: "canary.internal", ToPort: "8443"}},
+		{in: "::canary.internal:", want: AddressOverride{ToHost: "canary.internal"}},
+		{in: "api.example.com:443::8443", want: AddressOverride{Host: "api.example.com", Port: "443", ToPort: "8443"}},
+		{in: "api.example.com:443:[2001:db8::1]:443", want: AddressOverride{Host: "api.example.com", Port: "443", ToHost: "2001:db8::1", ToPort: "443"}},
+		{in: "api.example.com:443:canary.internal", wantErr: true},
+		{in: "api.example.com:443:canary.internal:8443:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseConnectTo(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseConnectTo(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseConnectTo(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteAddress(t *testing.T) {
+	overrides := []AddressOverride{
+		{Host: "www.example.com", Port: "443", ToHost: "203.0.113.10"},
+		{Host: "www.example.com", ToHost: "203.0.113.20"},
+		{Host: "api.example.com", Port: "443", ToHost: "canary.internal", ToPort: "8443"},
+		{Port: "8080", ToHost: "2001:db8::1"},
+	}
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"www.example.com:443", "203.0.113.10:443"},
+		{"WWW.Example.com:443", "203.0.113.10:443"},
+		{"www.example.com:80", "203.0.113.20:80"},
+		{"api.example.com:443", "canary.internal:8443"},
+		{"api.example.com:80", "api.example.com:80"},
+		{"other.example.com:8080", "[2001:db8::1]:8080"},
+		{"other.example.com:443", "other.example.com:443"},
+		{"no-port", "no-port"},
+	}
+	for _, tt := range tests {
+		if got := RewriteAddress(overrides, tt.address); got != tt.want {
+			t.Errorf("RewriteAddress(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
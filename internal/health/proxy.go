@@ -0,0 +1,149 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// DialFunc opens a connection to address, e.g. through a proxy
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// dial connects with d, or directly when d is nil
+func dial(d DialFunc, network, address string, timeout time.Duration) (net.Conn, error) {
+	if d == nil {
+		return net.DialTimeout(network, address, timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d(ctx, network, address)
+}
+
+// ProxyDialer returns a DialFunc that tunnels TCP connections through
+// proxyURL (http, https or socks5, with optional user:password) unless the
+// destination matches noProxy, a comma-separated list in NO_PROXY format
+func ProxyDialer(proxyURL, noProxy string) (DialFunc, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	selectProxy := (&httpproxy.Config{HTTPSProxy: proxyURL, NoProxy: noProxy}).ProxyFunc()
+	direct := &net.Dialer{}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		// Every destination is looked up as https so one proxy covers all
+		via, err := selectProxy(&url.URL{Scheme: "https", Host: address})
+		if err != nil {
+			return nil, err
+		}
+		if via == nil {
+			return direct.DialContext(ctx, network, address)
+		}
+
+		switch via.Scheme {
+		case "socks5", "socks5h":
+			var auth *proxy.Auth
+			if via.User != nil {
+				password, _ := via.User.Password()
+				auth = &proxy.Auth{User: via.User.Username(), Password: password}
+			}
+			socks, err := proxy.SOCKS5("tcp", proxyAddress(via), auth, direct)
+			if err != nil {
+				return nil, err
+			}
+			return socks.(proxy.ContextDialer).DialContext(ctx, network, address)
+		default:
+			return dialConnect(ctx, direct, via, address)
+		}
+	}, nil
+}
+
+// dialConnect opens a tunnel to address with an HTTP CONNECT request
+func dialConnect(ctx context.Context, direct *net.Dialer, via *url.URL, address string) (net.Conn, error) {
+	conn, err := direct.DialContext(ctx, "tcp", proxyAddress(via))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if via.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: via.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy TLS: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if via.User != nil {
+		password, _ := via.User.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(via.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+creds)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT: %s", resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn reads through r first, in case the server's first bytes
+// arrived together with the CONNECT response
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// proxyAddress is the proxy's host:port, with the scheme's default port
+func proxyAddress(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "1080"
+	switch u.Scheme {
+	case "http":
+		port = "80"
+	case "https":
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
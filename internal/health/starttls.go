@@ -0,0 +1,255 @@
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// StartTLSPorts are the default ports for each supported STARTTLS protocol
+var StartTLSPorts = map[string]int{
+	"smtp":     587,
+	"imap":     143,
+	"pop3":     110,
+	"ftp":      21,
+	"ldap":     389,
+	"postgres": 5432,
+}
+
+// startTLS negotiates a TLS upgrade on a plain connection so that the
+// handshake can follow
+func startTLS(conn net.Conn, protocol string) error {
+	switch protocol {
+	case "smtp":
+		return startTLSSMTP(conn)
+	case "imap":
+		return startTLSIMAP(conn)
+	case "pop3":
+		return startTLSPOP3(conn)
+	case "ftp":
+		return startTLSFTP(conn)
+	case "ldap":
+		return startTLSLDAP(conn)
+	case "postgres":
+		return startTLSPostgres(conn)
+	}
+	return fmt.Errorf("unsupported starttls protocol %q", protocol)
+}
+
+func startTLSSMTP(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, _, err := readReply(r, "220"); err != nil {
+		return fmt.Errorf("smtp greeting: %w", err)
+	}
+
+	if _, err := io.WriteString(conn, "EHLO gopunch\r\n"); err != nil {
+		return err
+	}
+	_, lines, err := readReply(r, "250")
+	if err != nil {
+		return fmt.Errorf("smtp EHLO: %w", err)
+	}
+	supported := false
+	for _, line := range lines {
+		if strings.EqualFold(strings.TrimSpace(line[4:]), "STARTTLS") {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("smtp server does not offer STARTTLS")
+	}
+
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	if _, _, err := readReply(r, "220"); err != nil {
+		return fmt.Errorf("smtp STARTTLS: %w", err)
+	}
+	return nil
+}
+
+func startTLSFTP(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, _, err := readReply(r, "220"); err != nil {
+		return fmt.Errorf("ftp greeting: %w", err)
+	}
+	if _, err := io.WriteString(conn, "AUTH TLS\r\n"); err != nil {
+		return err
+	}
+	if _, _, err := readReply(r, "234"); err != nil {
+		return fmt.Errorf("ftp AUTH TLS: %w", err)
+	}
+	return nil
+}
+
+// readReply reads an SMTP/FTP style reply, following "code-" continuation
+// lines, and fails unless the final code is want
+func readReply(r *bufio.Reader, want string) (string, []string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", lines, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) < 4 {
+			return "", lines, fmt.Errorf("malformed reply %q", line)
+		}
+		lines = append(lines, line)
+		if line[3] == '-' {
+			continue
+		}
+		code := line[:3]
+		if code != want {
+			return code, lines, fmt.Errorf("unexpected reply %q", line)
+		}
+		return code, lines, nil
+	}
+}
+
+func startTLSIMAP(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("imap greeting: unexpected reply %q", strings.TrimSpace(greeting))
+	}
+
+	if _, err := io.WriteString(conn, "a1 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("imap STARTTLS: %w", err)
+		}
+		if !strings.HasPrefix(line, "a1 ") {
+			continue // untagged response
+		}
+		if !strings.HasPrefix(line, "a1 OK") {
+			return fmt.Errorf("imap STARTTLS: unexpected reply %q", strings.TrimSpace(line))
+		}
+		return nil
+	}
+}
+
+func startTLSPOP3(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("pop3 greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("pop3 greeting: unexpected reply %q", strings.TrimSpace(greeting))
+	}
+
+	if _, err := io.WriteString(conn, "STLS\r\n"); err != nil {
+		return err
+	}
+	reply, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("pop3 STLS: %w", err)
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return fmt.Errorf("pop3 STLS: unexpected reply %q", strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// ldapStartTLS is an ExtendedRequest (message ID 1) for the StartTLS OID
+// 1.3.6.1.4.1.1466.20037
+var ldapStartTLS = append([]byte{0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16},
+	"1.3.6.1.4.1.1466.20037"...)
+
+func startTLSLDAP(conn net.Conn) error {
+	if _, err := conn.Write(ldapStartTLS); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+	msg, err := readBER(r, 0x30)
+	if err != nil {
+		return fmt.Errorf("ldap StartTLS: %w", err)
+	}
+
+	// LDAPMessage: messageID INTEGER, then ExtendedResponse [APPLICATION 24]
+	mr := bufio.NewReader(bytes.NewReader(msg))
+	if _, err := readBER(mr, 0x02); err != nil {
+		return fmt.Errorf("ldap StartTLS: %w", err)
+	}
+	resp, err := readBER(mr, 0x78)
+	if err != nil {
+		return fmt.Errorf("ldap StartTLS: %w", err)
+	}
+	code, err := readBER(bufio.NewReader(bytes.NewReader(resp)), 0x0a)
+	if err != nil || len(code) != 1 {
+		return fmt.Errorf("ldap StartTLS: malformed response")
+	}
+	if code[0] != 0 {
+		return fmt.Errorf("ldap StartTLS: result code %d", code[0])
+	}
+	return nil
+}
+
+// readBER reads one BER element with the given tag and returns its content
+func readBER(r *bufio.Reader, tag byte) ([]byte, error) {
+	t, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if t != tag {
+		return nil, fmt.Errorf("unexpected BER tag 0x%02x", t)
+	}
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length := int(b)
+	if b&0x80 != 0 {
+		n := int(b & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("unsupported BER length")
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxProbeResponse {
+		return nil, fmt.Errorf("BER element too large")
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// postgresSSLRequest is the SSLRequest startup message
+var postgresSSLRequest = binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 8), 80877103)
+
+func startTLSPostgres(conn net.Conn) error {
+	if _, err := conn.Write(postgresSSLRequest); err != nil {
+		return err
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("postgres SSLRequest: %w", err)
+	}
+	if reply[0] != 'S' {
+		return fmt.Errorf("postgres server does not support SSL")
+	}
+	return nil
+}
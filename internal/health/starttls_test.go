@@ -0,0 +1,294 @@
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exchange is one turn of a server script: wait for the client to send
+// expect (nothing for a greeting), then answer with reply
+type exchange struct {
+	expect string
+	reply  string
+}
+
+// serveScript plays script on conn and reports whether the client sent
+// what was expected
+func serveScript(conn net.Conn, script []exchange) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		defer conn.Close()
+		for _, ex := range script {
+			if ex.expect != "" {
+				got := make([]byte, len(ex.expect))
+				if _, err := io.ReadFull(conn, got); err != nil {
+					done <- err
+					return
+				}
+				if string(got) != ex.expect {
+					done <- fmt.Errorf("client sent %q, want %q", got, ex.expect)
+					return
+				}
+			}
+			if _, err := io.WriteString(conn, ex.reply); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	return done
+}
+
+var (
+	ldapSuccess = "\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x00\x04\x00\x04\x00"
+	ldapError   = "\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x02\x04\x00\x04\x00"
+)
+
+func TestStartTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		script   []exchange
+		wantErr  string
+	}{
+		{
+			name:     "smtp",
+			protocol: "smtp",
+			script: []exchange{
+				{reply: "220-mx.example.com ESMTP\r\n220 ready\r\n"},
+				{expect: "EHLO gopunch\r\n", reply: "250-mx.example.com\r\n250-PIPELINING\r\n250-STARTTLS\r\n250 8BITMIME\r\n"},
+				{expect: "STARTTLS\r\n", reply: "220 go ahead\r\n"},
+			},
+		},
+		{
+			name:     "smtp last capability",
+			protocol: "smtp",
+			script: []exchange{
+				{reply: "220 ready\r\n"},
+				{expect: "EHLO gopunch\r\n", reply: "250-mx.example.com\r\n250 starttls\r\n"},
+				{expect: "STARTTLS\r\n", reply: "220 go ahead\r\n"},
+			},
+		},
+		{
+			name:     "smtp without starttls",
+			protocol: "smtp",
+			script: []exchange{
+				{reply: "220 ready\r\n"},
+				{expect: "EHLO gopunch\r\n", reply: "250-mx.example.com\r\n250 8BITMIME\r\n"},
+			},
+			wantErr: "does not offer STARTTLS",
+		},
+		{
+			name:     "smtp rejected",
+			protocol: "smtp",
+			script:   []exchange{{reply: "554 no service\r\n"}},
+			wantErr:  `smtp greeting: unexpected reply "554 no service"`,
+		},
+		{
+			name:     "smtp starttls refused",
+			protocol: "smtp",
+			script: []exchange{
+				{reply: "220 ready\r\n"},
+				{expect: "EHLO gopunch\r\n", reply: "250 STARTTLS\r\n"},
+				{expect: "STARTTLS\r\n", reply: "454 TLS not available\r\n"},
+			},
+			wantErr: "smtp STARTTLS",
+		},
+		{
+			name:     "ftp",
+			protocol: "ftp",
+			script: []exchange{
+				{reply: "220 FTP ready\r\n"},
+				{expect: "AUTH TLS\r\n", reply: "234 AUTH TLS successful\r\n"},
+			},
+		},
+		{
+			name:     "ftp without tls",
+			protocol: "ftp",
+			script: []exchange{
+				{reply: "220 FTP ready\r\n"},
+				{expect: "AUTH TLS\r\n", reply: "500 unknown command\r\n"},
+			},
+			wantErr: "ftp AUTH TLS",
+		},
+		{
+			name:     "imap",
+			protocol: "imap",
+			script: []exchange{
+				{reply: "* OK IMAP4rev1 ready\r\n"},
+				{expect: "a1 STARTTLS\r\n", reply: "* CAPABILITY IMAP4rev1\r\na1 OK Begin TLS negotiation\r\n"},
+			},
+		},
+		{
+			name:     "imap refused",
+			protocol: "imap",
+			script: []exchange{
+				{reply: "* OK IMAP4rev1 ready\r\n"},
+				{expect: "a1 STARTTLS\r\n", reply: "a1 NO not now\r\n"},
+			},
+			wantErr: `imap STARTTLS: unexpected reply "a1 NO not now"`,
+		},
+		{
+			name:     "imap bye",
+			protocol: "imap",
+			script:   []exchange{{reply: "* BYE shutting down\r\n"}},
+			wantErr:  "imap greeting",
+		},
+		{
+			name:     "pop3",
+			protocol: "pop3",
+			script: []exchange{
+				{reply: "+OK POP3 ready\r\n"},
+				{expect: "STLS\r\n", reply: "+OK Begin TLS\r\n"},
+			},
+		},
+		{
+			name:     "pop3 refused",
+			protocol: "pop3",
+			script: []exchange{
+				{reply: "+OK POP3 ready\r\n"},
+				{expect: "STLS\r\n", reply: "-ERR not supported\r\n"},
+			},
+			wantErr: "pop3 STLS",
+		},
+		{
+			name:     "ldap",
+			protocol: "ldap",
+			script:   []exchange{{expect: string(ldapStartTLS), reply: ldapSuccess}},
+		},
+		{
+			name:     "ldap error",
+			protocol: "ldap",
+			script:   []exchange{{expect: string(ldapStartTLS), reply: ldapError}},
+			wantErr:  "result code 2",
+		},
+		{
+			name:     "ldap garbage",
+			protocol: "ldap",
+			script:   []exchange{{expect: string(ldapStartTLS), reply: "HTTP/1.1 400\r\n"}},
+			wantErr:  "unexpected BER tag",
+		},
+		{
+			name:     "postgres",
+			protocol: "postgres",
+			script:   []exchange{{expect: string(postgresSSLRequest), reply: "S"}},
+		},
+		{
+			name:     "postgres without ssl",
+			protocol: "postgres",
+			script:   []exchange{{expect: string(postgresSSLRequest), reply: "N"}},
+			wantErr:  "does not support SSL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			client.SetDeadline(time.Now().Add(5 * time.Second))
+			done := serveScript(server, tt.script)
+
+			err := startTLS(client, tt.protocol)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("startTLS() = %v", err)
+				}
+				if err := <-done; err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("startTLS() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		wantCode  string
+		wantLines int
+		wantErr   bool
+	}{
+		{name: "single line", input: "250 OK\r\n", want: "250", wantCode: "250", wantLines: 1},
+		{name: "continuation", input: "250-first\r\n250-second\r\n250 last\r\n", want: "250", wantCode: "250", wantLines: 3},
+		{name: "bare newlines", input: "220-a\n220 b\n", want: "220", wantCode: "220", wantLines: 2},
+		{name: "stops at final line", input: "250 OK\r\n250 next\r\n", want: "250", wantCode: "250", wantLines: 1},
+		{name: "other code", input: "550-no\r\n550 such user\r\n", want: "250", wantCode: "550", wantLines: 2, wantErr: true},
+		{name: "malformed", input: "25\r\n", want: "250", wantErr: true},
+		{name: "truncated", input: "250-first\r\n", want: "250", wantLines: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, lines, err := readReply(bufio.NewReader(strings.NewReader(tt.input)), tt.want)
+			if (err != nil) != tt.wantErr || code != tt.wantCode || len(lines) != tt.wantLines {
+				t.Errorf("readReply() = %q, %q, %v, want %q with %d lines, error %v", code, lines, err, tt.wantCode, tt.wantLines, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadBER(t *testing.T) {
+	long := bytes.Repeat([]byte{'x'}, 300)
+	tests := []struct {
+		name    string
+		input   []byte
+		tag     byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "short form", input: []byte{0x04, 0x03, 'a', 'b', 'c'}, tag: 0x04, want: []byte("abc")},
+		{name: "empty", input: []byte{0x04, 0x00}, tag: 0x04, want: []byte{}},
+		{name: "long form", input: append([]byte{0x04, 0x82, 0x01, 0x2c}, long...), tag: 0x04, want: long},
+		{name: "long form one byte", input: append([]byte{0x04, 0x81, 0x03}, "abc"...), tag: 0x04, want: []byte("abc")},
+		{name: "wrong tag", input: []byte{0x02, 0x01, 0x01}, tag: 0x04, wantErr: true},
+		{name: "indefinite length", input: []byte{0x30, 0x80, 0x00, 0x00}, tag: 0x30, wantErr: true},
+		{name: "length too wide", input: []byte{0x30, 0x85, 0, 0, 0, 0, 1}, tag: 0x30, wantErr: true},
+		{name: "too large", input: []byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff}, tag: 0x30, wantErr: true},
+		{name: "truncated", input: []byte{0x04, 0x05, 'a'}, tag: 0x04, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readBER(bufio.NewReader(bytes.NewReader(tt.input)), tt.tag)
+			if (err != nil) != tt.wantErr || (!tt.wantErr && !bytes.Equal(got, tt.want)) {
+				t.Errorf("readBER() = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLDAPStartTLSRequest(t *testing.T) {
+	// LDAPMessage { messageID 1, ExtendedRequest { requestName OID } }
+	msg, err := readBER(bufio.NewReader(bytes.NewReader(ldapStartTLS)), 0x30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg)+2 != len(ldapStartTLS) {
+		t.Fatalf("message has %d trailing bytes", len(ldapStartTLS)-len(msg)-2)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(msg))
+	id, err := readBER(r, 0x02)
+	if err != nil || !bytes.Equal(id, []byte{1}) {
+		t.Fatalf("messageID = %v, %v, want 1", id, err)
+	}
+	req, err := readBER(r, 0x77)
+	if err != nil {
+		t.Fatalf("ExtendedRequest: %v", err)
+	}
+	name, err := readBER(bufio.NewReader(bytes.NewReader(req)), 0x80)
+	if err != nil || string(name) != "1.3.6.1.4.1.1466.20037" {
+		t.Fatalf("requestName = %q, %v", name, err)
+	}
+}
@@ -0,0 +1,268 @@
+package health
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// CertInfo describes one certificate in a chain
+type CertInfo struct {
+	Subject            string
+	Issuer             string
+	Serial             string
+	NotBefore          time.Time
+	NotAfter           time.Time
+	DaysLeft           int
+	KeyType            string
+	KeyBits            int
+	SignatureAlgorithm string
+	IsCA               bool
+}
+
+// TLSPolicy sets the minimum acceptable TLS configuration. The zero value
+// accepts anything that verifies.
+type TLSPolicy struct {
+	MinVersion          uint16 // Lowest accepted protocol version, e.g. tls.VersionTLS12
+	MinRSABits          int    // Smallest accepted RSA key anywhere in the chain
+	AllowWeakSignatures bool   // Accept MD5 and SHA-1 signatures below the root
+	RequireOCSPStaple   bool   // Fail unless a good OCSP response is stapled
+}
+
+// DefaultTLSPolicy requires TLS 1.2, 2048-bit RSA keys and SHA-2 signatures
+func DefaultTLSPolicy() TLSPolicy {
+	return TLSPolicy{
+		MinVersion: tls.VersionTLS12,
+		MinRSABits: 2048,
+	}
+}
+
+// ParseTLSVersion converts "1.0" to "1.3" (optionally prefixed with "TLS")
+// to a tls.Version* constant
+func ParseTLSVersion(s string) (uint16, error) {
+	v := strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(s), "TLS"))
+	switch v {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q", s)
+}
+
+// TLSClientOptions configures the client side of a TLS connection
+type TLSClientOptions struct {
+	CertFile   string // PEM client certificate for mutual TLS
+	KeyFile    string // PEM private key for CertFile
+	CAFile     string // PEM bundle of trusted roots; the system roots if empty
+	ServerName string // SNI and verification name; the target host if empty
+	MinVersion uint16
+	Insecure   bool
+}
+
+// Config builds a tls.Config for connecting to host
+func (o TLSClientOptions) Config(host string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         host,
+		MinVersion:         o.MinVersion,
+		InsecureSkipVerify: o.Insecure,
+	}
+	if o.ServerName != "" {
+		cfg.ServerName = o.ServerName
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate needs both a cert and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+// weakSignatures are algorithms considered broken for certificate signing
+var weakSignatures = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// inspectTLS fills result from a completed handshake: verifies the chain
+// for host against roots (the system roots when nil), records connection
+// and certificate details and applies policy
+func inspectTLS(result *SSLResult, state tls.ConnectionState, host string, roots *x509.CertPool, policy TLSPolicy) {
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		result.Error = fmt.Errorf("no certificates found")
+		result.Valid = false
+		return
+	}
+
+	leaf := certs[0]
+	result.Issuer = leaf.Issuer.CommonName
+	result.Subject = leaf.Subject.CommonName
+	result.Serial = leaf.SerialNumber.Text(16)
+	result.NotBefore = leaf.NotBefore
+	result.NotAfter = leaf.NotAfter
+	result.DaysLeft = int(time.Until(leaf.NotAfter).Hours() / 24)
+
+	result.Version = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	result.KeyType, result.KeyBits = publicKeyInfo(leaf.PublicKey)
+	result.SignatureAlgorithm = leaf.SignatureAlgorithm.String()
+	result.DNSNames = append(result.DNSNames, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		result.DNSNames = append(result.DNSNames, ip.String())
+	}
+	result.HostnameMatch = leaf.VerifyHostname(host) == nil
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	chains, verifyErr := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+
+	chain := certs
+	if len(chains) > 0 {
+		chain = chains[0]
+	}
+	result.ChainDaysLeft = result.DaysLeft
+	for _, c := range chain {
+		info := certInfo(c)
+		result.Chain = append(result.Chain, info)
+		if info.DaysLeft < result.ChainDaysLeft {
+			result.ChainDaysLeft = info.DaysLeft
+		}
+	}
+
+	if len(state.OCSPResponse) > 0 {
+		result.OCSPStapled = true
+		result.OCSPStatus = "unknown"
+		var issuer *x509.Certificate
+		if len(chain) > 1 {
+			issuer = chain[1]
+		}
+		if resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer); err == nil {
+			switch resp.Status {
+			case ocsp.Good:
+				result.OCSPStatus = "good"
+			case ocsp.Revoked:
+				result.OCSPStatus = "revoked"
+			}
+		}
+	}
+
+	result.Violations = checkPolicy(result, state, chain, policy)
+
+	now := time.Now()
+	result.Valid = now.Before(leaf.NotAfter) && now.After(leaf.NotBefore) &&
+		verifyErr == nil && len(result.Violations) == 0
+
+	if verifyErr != nil {
+		result.Error = verifyErr
+	} else if len(result.Violations) > 0 {
+		result.Error = fmt.Errorf("TLS policy: %s", strings.Join(result.Violations, "; "))
+	}
+}
+
+// checkPolicy lists the ways a connection falls short of policy
+func checkPolicy(result *SSLResult, state tls.ConnectionState, chain []*x509.Certificate, policy TLSPolicy) []string {
+	var violations []string
+
+	if policy.MinVersion != 0 && state.Version < policy.MinVersion {
+		violations = append(violations, fmt.Sprintf("%s is below %s",
+			tls.VersionName(state.Version), tls.VersionName(policy.MinVersion)))
+	}
+
+	for _, c := range chain {
+		// A root's self-signature is never checked, so its algorithm doesn't matter
+		selfSigned := bytes.Equal(c.RawIssuer, c.RawSubject)
+
+		if key, ok := c.PublicKey.(*rsa.PublicKey); ok && policy.MinRSABits > 0 && key.N.BitLen() < policy.MinRSABits {
+			violations = append(violations, fmt.Sprintf("%d-bit RSA key on %s", key.N.BitLen(), certName(c)))
+		}
+		if !policy.AllowWeakSignatures && !selfSigned && weakSignatures[c.SignatureAlgorithm] {
+			violations = append(violations, fmt.Sprintf("weak %s signature on %s", c.SignatureAlgorithm, certName(c)))
+		}
+	}
+
+	if result.OCSPStatus == "revoked" {
+		violations = append(violations, "stapled OCSP response says the certificate is revoked")
+	} else if policy.RequireOCSPStaple && result.OCSPStatus != "good" {
+		violations = append(violations, "no valid OCSP response stapled")
+	}
+
+	return violations
+}
+
+func certInfo(c *x509.Certificate) CertInfo {
+	keyType, keyBits := publicKeyInfo(c.PublicKey)
+	return CertInfo{
+		Subject:            certName(c),
+		Issuer:             c.Issuer.CommonName,
+		Serial:             c.SerialNumber.Text(16),
+		NotBefore:          c.NotBefore,
+		NotAfter:           c.NotAfter,
+		DaysLeft:           int(time.Until(c.NotAfter).Hours() / 24),
+		KeyType:            keyType,
+		KeyBits:            keyBits,
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		IsCA:               c.IsCA,
+	}
+}
+
+// certName is the subject common name, or the full subject when it has none
+func certName(c *x509.Certificate) string {
+	if c.Subject.CommonName != "" {
+		return c.Subject.CommonName
+	}
+	return c.Subject.String()
+}
+
+func publicKeyInfo(key any) (string, int) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return "unknown", 0
+}
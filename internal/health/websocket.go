@@ -0,0 +1,97 @@
+package health
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketOptions configures a WebSocket check
+type WebSocketOptions struct {
+	Timeout  time.Duration
+	Insecure bool
+	Headers  map[string]string
+	Send     []byte   // Message sent after the handshake, if any
+	Expect   *Matcher // Pattern the first reply must match
+}
+
+// WebSocketResult represents WebSocket check result
+type WebSocketResult struct {
+	URL           string
+	Connected     bool
+	StatusCode    int
+	HandshakeTime time.Duration
+	RoundTrip     time.Duration // Time from sending the message to the reply
+	Reply         []byte
+	Duration      time.Duration
+	Error         error
+}
+
+// CheckWebSocket performs the upgrade handshake and, when a message is
+// configured, waits for a reply matching the expectation
+func CheckWebSocket(url string, opts WebSocketOptions) WebSocketResult {
+	result := WebSocketResult{URL: url}
+	start := time.Now()
+	deadline := start.Add(opts.Timeout)
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: opts.Timeout,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: opts.Insecure},
+	}
+
+	header := http.Header{}
+	header.Set("User-Agent", "GoPunch/2.0")
+	for key, value := range opts.Headers {
+		header.Set(key, value)
+	}
+
+	conn, resp, err := dialer.Dial(url, header)
+	result.HandshakeTime = time.Since(start)
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("handshake failed: %w", err)
+		result.Duration = time.Since(start)
+		return result
+	}
+	defer conn.Close()
+
+	if len(opts.Send) > 0 || opts.Expect != nil {
+		sent := time.Now()
+		conn.SetWriteDeadline(deadline)
+		conn.SetReadDeadline(deadline)
+
+		if len(opts.Send) > 0 {
+			if err := conn.WriteMessage(websocket.TextMessage, opts.Send); err != nil {
+				result.Error = fmt.Errorf("send failed: %w", err)
+				result.Duration = time.Since(start)
+				return result
+			}
+		}
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				result.Error = fmt.Errorf("no matching reply: %w", err)
+				result.Duration = time.Since(start)
+				return result
+			}
+			result.Reply = msg
+			if opts.Expect == nil || opts.Expect.Match(msg) {
+				break
+			}
+		}
+		result.RoundTrip = time.Since(sent)
+	}
+
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+
+	result.Duration = time.Since(start)
+	result.Connected = true
+	return result
+}